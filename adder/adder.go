@@ -18,6 +18,7 @@ import (
 	balanced "github.com/ipfs/go-unixfs/importer/balanced"
 	ihelper "github.com/ipfs/go-unixfs/importer/helpers"
 	w3fs "github.com/web3-storage/go-w3s-client/fs"
+	"github.com/web3-storage/go-w3s-client/logging"
 )
 
 const chnkr = "size-1048576"
@@ -27,20 +28,86 @@ const rawLeaves = true
 var cidBuilder = dag.V1CidPrefix()
 var liveCacheSize = uint64(256 << 10)
 
+// Option is an option configuring an Adder.
+type Option func(adder *Adder)
+
+// WithLogger sets the logger used to report progress and failures.
+func WithLogger(log logging.Logger) Option {
+	return func(adder *Adder) {
+		if log != nil {
+			adder.log = log
+		}
+	}
+}
+
+// Cache lets an Adder reuse the DAGs of files imported by a previous add
+// instead of chunking them again.
+type Cache interface {
+	// Get returns the root node of the DAG previously imported for the file at
+	// path, if the file is unchanged.
+	Get(path string, info fs.FileInfo) (ipld.Node, bool)
+	// Put records the root node of the DAG imported for the file at path.
+	Put(path string, info fs.FileInfo, nd ipld.Node)
+}
+
+// WithCache sets a cache of previously imported files.
+func WithCache(cache Cache) Option {
+	return func(adder *Adder) {
+		adder.cache = cache
+	}
+}
+
 // NewAdder Returns a new Adder used for a file add operation.
-func NewAdder(ctx context.Context, ds ipld.DAGService) (*Adder, error) {
-	return &Adder{
+func NewAdder(ctx context.Context, ds ipld.DAGService, options ...Option) (*Adder, error) {
+	cds := &countingDAGService{DAGService: ds}
+	adder := &Adder{
 		ctx:        ctx,
-		dagService: ds,
-	}, nil
+		dagService: cds,
+		counter:    cds,
+		log:        logging.Nop,
+	}
+	for _, opt := range options {
+		opt(adder)
+	}
+	return adder, nil
 }
 
 // Adder is an filesystem interface adder for web3.storage.
 type Adder struct {
 	ctx        context.Context
 	dagService ipld.DAGService
+	counter    *countingDAGService
 	mroot      *mfs.Root
 	liveNodes  uint64
+	log        logging.Logger
+	cache      Cache
+}
+
+// countingDAGService counts the blocks and bytes added to a DAG service.
+type countingDAGService struct {
+	ipld.DAGService
+	blocks uint64
+	bytes  uint64
+}
+
+func (ds *countingDAGService) Add(ctx context.Context, nd ipld.Node) error {
+	err := ds.DAGService.Add(ctx, nd)
+	if err == nil {
+		ds.blocks++
+		ds.bytes += uint64(len(nd.RawData()))
+	}
+	return err
+}
+
+func (ds *countingDAGService) AddMany(ctx context.Context, nds []ipld.Node) error {
+	err := ds.DAGService.AddMany(ctx, nds)
+	if err == nil {
+		for _, nd := range nds {
+			ds.blocks++
+			ds.bytes += uint64(len(nd.RawData()))
+		}
+	}
+	return err
 }
 
 func (adder *Adder) Add(file fs.File, dirname string, fsys fs.FS) (cid.Cid, error) {
@@ -54,6 +121,37 @@ func (adder *Adder) Add(file fs.File, dirname string, fsys fs.FS) (cid.Cid, erro
 	}
 
 	nd, err := adder.addAll(file, fi, dirname, fsys)
+	if err != nil {
+		adder.log.Errorf("adding %s: %v", fi.Name(), err)
+		return cid.Undef, err
+	}
+	adder.log.Infof("added %s as %s: %d blocks, %d bytes", fi.Name(), nd.Cid(), adder.counter.blocks, adder.counter.bytes)
+	return nd.Cid(), nil
+}
+
+// Import adds the file and returns the root CID of its DAG. Unlike Add, a
+// directory is not wrapped in another.
+func (adder *Adder) Import(file fs.File, dirname string, fsys fs.FS) (cid.Cid, error) {
+	fi, err := file.Stat()
+	if err != nil {
+		return cid.Undef, err
+	}
+	root, err := adder.Add(file, dirname, fsys)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if !fi.IsDir() {
+		return root, nil
+	}
+	mr, err := adder.MfsRoot()
+	if err != nil {
+		return cid.Undef, err
+	}
+	dir, err := mr.GetDirectory().Child(fi.Name())
+	if err != nil {
+		return cid.Undef, err
+	}
+	nd, err := dir.GetNode()
 	if err != nil {
 		return cid.Undef, err
 	}
@@ -181,6 +279,7 @@ func (adder *Adder) addFileOrDir(path string, f fs.File, fi fs.FileInfo, dirname
 
 	if adder.liveNodes >= liveCacheSize {
 		// TODO: A smarter cache that uses some sort of lru cache with an eviction handler
+		adder.log.Debugf("flushing directories after %d live nodes", adder.liveNodes)
 		mr, err := adder.MfsRoot()
 		if err != nil {
 			return err
@@ -197,14 +296,24 @@ func (adder *Adder) addFileOrDir(path string, f fs.File, fi fs.FileInfo, dirname
 	if fi.IsDir() {
 		return adder.addDir(path, f, dirname, fsys, toplevel)
 	}
-	return adder.addFile(path, f)
+	return adder.addFile(path, f, fi)
 }
 
-func (adder *Adder) addFile(path string, f fs.File) error {
+func (adder *Adder) addFile(path string, f fs.File, fi fs.FileInfo) error {
+	if adder.cache != nil {
+		if nd, ok := adder.cache.Get(path, fi); ok {
+			adder.log.Debugf("reusing %s for unchanged file %s", nd.Cid(), path)
+			return adder.addNode(nd, path)
+		}
+	}
 	dagnode, err := adder.add(f)
 	if err != nil {
 		return err
 	}
+	adder.log.Debugf("added file %s as %s", path, dagnode.Cid())
+	if adder.cache != nil {
+		adder.cache.Put(path, fi, dagnode)
+	}
 	// patch it into the root
 	return adder.addNode(dagnode, path)
 }
@@ -0,0 +1,169 @@
+package w3s
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	blocks "github.com/ipfs/go-block-format"
+	bserv "github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// ErrReadOnly is returned when writing to the blockstore of a client with no
+// datastore configured.
+var ErrReadOnly = errors.New("read-only blockstore")
+
+// Blockstore returns the blockstore backed by the datastore the client was
+// configured with, holding the blocks of earlier Put, PutCar, Get and Sync
+// calls. If no datastore was configured each operation uses its own in-memory
+// store that is discarded when it completes, and an empty store that fails
+// writes with ErrReadOnly is returned.
+func (c *client) Blockstore() blockstore.Blockstore {
+	if c.bs == nil {
+		return emptyBlockstore{}
+	}
+	return c.bs
+}
+
+// emptyBlockstore is a blockstore holding no blocks that cannot be written.
+type emptyBlockstore struct{}
+
+func (emptyBlockstore) DeleteBlock(context.Context, cid.Cid) error { return ErrReadOnly }
+func (emptyBlockstore) Has(context.Context, cid.Cid) (bool, error) { return false, nil }
+func (emptyBlockstore) Get(_ context.Context, c cid.Cid) (blocks.Block, error) {
+	return nil, ipld.ErrNotFound{Cid: c}
+}
+func (emptyBlockstore) GetSize(_ context.Context, c cid.Cid) (int, error) {
+	return -1, ipld.ErrNotFound{Cid: c}
+}
+func (emptyBlockstore) Put(context.Context, blocks.Block) error       { return ErrReadOnly }
+func (emptyBlockstore) PutMany(context.Context, []blocks.Block) error { return ErrReadOnly }
+func (emptyBlockstore) HashOnRead(bool)                               {}
+func (emptyBlockstore) AllKeysChan(context.Context) (<-chan cid.Cid, error) {
+	ch := make(chan cid.Cid)
+	close(ch)
+	return ch, nil
+}
+
+// blockService returns the block service an operation reads and writes blocks
+// with: the shared one if a datastore was configured, otherwise a new
+// in-memory one.
+func (c *client) blockService() bserv.BlockService {
+	if c.bsvc != nil {
+		return c.bsvc
+	}
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	return bserv.New(bs, nil)
+}
+
+// trackUpload returns the block service an upload adds blocks with, and a
+// function to call with the result of the upload once it completes. If upload
+// cleanup is enabled the blocks the upload added are then removed.
+func (c *client) trackUpload(ctx context.Context, bsvc bserv.BlockService) (bserv.BlockService, func(error)) {
+	if !c.cfg.cleanup || c.bs == nil {
+		return bsvc, func(error) {}
+	}
+	t := newUploadTracker(bsvc, c.bs, &c.refs)
+	return t, func(err error) {
+		n, rerr := t.release(ctx, err == nil)
+		if rerr != nil {
+			c.cfg.log.Warnf("removing uploaded blocks: %v", rerr)
+			return
+		}
+		if n > 0 {
+			c.cfg.log.Debugf("removed %d uploaded blocks", n)
+		}
+	}
+}
+
+// blockRefs counts the in-flight uploads using each block, so that blocks
+// removed after an upload are not still needed by a concurrent one.
+type blockRefs struct {
+	mu   sync.Mutex
+	refs map[cid.Cid]int
+}
+
+// uploadTracker is a block service recording the blocks added by an upload
+// that were not already in the blockstore, so they can be removed once
+// uploaded.
+type uploadTracker struct {
+	bserv.BlockService
+	bs   blockstore.Blockstore
+	refs *blockRefs
+
+	mu    sync.Mutex
+	used  []cid.Cid
+	added *cid.Set
+}
+
+func newUploadTracker(bsvc bserv.BlockService, bs blockstore.Blockstore, refs *blockRefs) *uploadTracker {
+	return &uploadTracker{BlockService: bsvc, bs: bs, refs: refs, added: cid.NewSet()}
+}
+
+// track takes a reference to the block and records whether it is new.
+func (t *uploadTracker) track(ctx context.Context, c cid.Cid) error {
+	t.refs.mu.Lock()
+	t.refs.refs[c]++
+	has, err := t.bs.Has(ctx, c)
+	t.refs.mu.Unlock()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.used = append(t.used, c)
+	if err == nil && !has {
+		t.added.Add(c)
+	}
+	return err
+}
+
+func (t *uploadTracker) AddBlock(ctx context.Context, b blocks.Block) error {
+	if err := t.track(ctx, b.Cid()); err != nil {
+		return err
+	}
+	return t.BlockService.AddBlock(ctx, b)
+}
+
+func (t *uploadTracker) AddBlocks(ctx context.Context, bs []blocks.Block) error {
+	for _, b := range bs {
+		if err := t.track(ctx, b.Cid()); err != nil {
+			return err
+		}
+	}
+	return t.BlockService.AddBlocks(ctx, bs)
+}
+
+// release drops the references taken by the upload. If remove is true the
+// blocks it added are deleted, unless another upload still uses them. It
+// returns the number of blocks deleted.
+func (t *uploadTracker) release(ctx context.Context, remove bool) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.refs.mu.Lock()
+	defer t.refs.mu.Unlock()
+
+	for _, c := range t.used {
+		if t.refs.refs[c]--; t.refs.refs[c] <= 0 {
+			delete(t.refs.refs, c)
+		}
+	}
+	if !remove {
+		return 0, nil
+	}
+	var n int
+	for _, c := range t.added.Keys() {
+		if _, ok := t.refs.refs[c]; ok {
+			continue
+		}
+		err := t.bs.DeleteBlock(ctx, c)
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,158 @@
+package w3s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	leveldb "github.com/ipfs/go-ds-leveldb"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/web3-storage/go-w3s-client/w3stest"
+)
+
+func countBlocks(t *testing.T, bs blockstore.Blockstore) int {
+	ch, err := bs.AllKeysChan(context.Background())
+	if err != nil {
+		t.Fatalf("failed to list blocks: %v", err)
+	}
+	var n int
+	for range ch {
+		n++
+	}
+	return n
+}
+
+func TestPerOperationBlockstore(t *testing.T) {
+	srv := w3stest.NewServer(w3stest.WithToken(validToken))
+	defer srv.Close()
+	client := newFakeClient(t, srv)
+
+	bs := client.Blockstore()
+	root := putFakeFile(t, client, "hello.txt", "hello")
+	if has, err := bs.Has(context.Background(), root); err != nil || has {
+		t.Fatalf("got %t, %v for uploaded root with no datastore configured, wanted false", has, err)
+	}
+	if err := bs.DeleteBlock(context.Background(), root); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("got error %v, wanted %v", err, ErrReadOnly)
+	}
+	if _, err := client.Sync(context.Background(), t.TempDir()); err == nil {
+		t.Fatalf("sync with no datastore did not fail")
+	}
+
+	res, err := client.Get(context.Background(), root)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if _, _, err := res.Files(); err != nil {
+		t.Fatalf("failed to read files: %v", err)
+	}
+}
+
+func TestUploadCleanup(t *testing.T) {
+	srv := w3stest.NewServer(w3stest.WithToken(validToken))
+	defer srv.Close()
+	store := dssync.MutexWrap(ds.NewMapDatastore())
+
+	uploader, err := NewClient(WithEndpoint(srv.URL), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client, err := NewClient(WithEndpoint(srv.URL), WithToken(validToken), WithDatastore(store), WithUploadCleanup())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	bs := client.Blockstore()
+
+	// Blocks read by Get are cached.
+	cached := putFakeFile(t, uploader, "cached.txt", "cached")
+	res, err := client.Get(context.Background(), cached)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if _, _, err := res.Files(); err != nil {
+		t.Fatalf("failed to read files: %v", err)
+	}
+	n := countBlocks(t, bs)
+	if has, _ := bs.Has(context.Background(), cached); !has || n == 0 {
+		t.Fatalf("root %s was not cached", cached)
+	}
+
+	// Blocks imported by Put are removed once uploaded, except those that were
+	// already cached.
+	var roots []cid.Cid
+	roots = append(roots, putFakeFile(t, client, "new.txt", "new"))
+	roots = append(roots, putFakeFile(t, client, "cached.txt", "cached"))
+	if roots[1] != cached {
+		t.Fatalf("got root %s, wanted %s", roots[1], cached)
+	}
+	carRoots, err := client.PutCar(context.Background(), bytes.NewReader(multiRootCar(t)))
+	if err != nil {
+		t.Fatalf("failed to put car: %v", err)
+	}
+	roots = append(roots, carRoots...)
+	if got := countBlocks(t, bs); got != n {
+		t.Fatalf("got %d blocks after uploads, wanted %d", got, n)
+	}
+	for _, root := range roots {
+		if !srv.HasUpload(root) {
+			t.Fatalf("upload %s not found", root)
+		}
+	}
+}
+
+func TestPutCarKeepsBlocksWithoutCleanup(t *testing.T) {
+	srv := w3stest.NewServer(w3stest.WithToken(validToken))
+	defer srv.Close()
+	client, err := NewClient(WithEndpoint(srv.URL), WithToken(validToken), WithDatastore(dssync.MutexWrap(ds.NewMapDatastore())))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	roots, err := client.PutCar(context.Background(), bytes.NewReader(multiRootCar(t)))
+	if err != nil {
+		t.Fatalf("failed to put car: %v", err)
+	}
+	for _, root := range roots {
+		if has, _ := client.Blockstore().Has(context.Background(), root); !has {
+			t.Fatalf("root %s of uploaded CAR was not kept", root)
+		}
+	}
+}
+
+func TestCloseDatastore(t *testing.T) {
+	srv := w3stest.NewServer(w3stest.WithToken(validToken))
+	defer srv.Close()
+
+	path := t.TempDir()
+	client := newFakeClient(t, srv, WithDatastorePath(path))
+	root := putFakeFile(t, client, "hello.txt", "hello")
+	if err := client.Close(); err != nil {
+		t.Fatalf("failed to close client: %v", err)
+	}
+
+	// The datastore can only be opened again once it has been closed.
+	client = newFakeClient(t, srv, WithDatastorePath(path))
+	defer client.Close()
+	if has, err := client.Blockstore().Has(context.Background(), root); err != nil || !has {
+		t.Fatalf("got %t, %v for root in reopened datastore, wanted true", has, err)
+	}
+
+	// A datastore passed to the client is left open.
+	d, err := leveldb.NewDatastore(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("failed to open datastore: %v", err)
+	}
+	defer d.Close()
+	client = newFakeClient(t, srv, WithDatastore(d))
+	root = putFakeFile(t, client, "hello.txt", "hello")
+	if err := client.Close(); err != nil {
+		t.Fatalf("failed to close client: %v", err)
+	}
+	if has, err := blockstore.NewBlockstore(d).Has(context.Background(), root); err != nil || !has {
+		t.Fatalf("got %t, %v for root in datastore after close, wanted true", has, err)
+	}
+}
@@ -6,13 +6,21 @@ import (
 	"io"
 	"io/fs"
 	"net/http"
+	"net/url"
+	"time"
 
 	bserv "github.com/ipfs/go-blockservice"
 	"github.com/ipfs/go-cid"
 	ds "github.com/ipfs/go-datastore"
-	dssync "github.com/ipfs/go-datastore/sync"
+	leveldb "github.com/ipfs/go-ds-leveldb"
 	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/ipld/go-ipld-prime/datamodel"
 	w3http "github.com/web3-storage/go-w3s-client/http"
+	"github.com/web3-storage/go-w3s-client/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const clientName = "web3.storage/go"
@@ -20,23 +28,61 @@ const clientName = "web3.storage/go"
 // Client is a HTTP API client to the web3.storage service.
 type Client interface {
 	Get(context.Context, cid.Cid) (*w3http.Web3Response, error)
-	Put(context.Context, fs.File, ...PutOption) (cid.Cid, error)
-	PutCar(context.Context, io.Reader) (cid.Cid, error)
+	Put(context.Context, fs.File, ...PutOption) ([]cid.Cid, error)
+	PutCar(context.Context, io.Reader, ...PutOption) ([]cid.Cid, error)
 	Status(context.Context, cid.Cid) (*Status, error)
 	List(context.Context, ...ListOption) (*UploadIterator, error)
+	Delete(context.Context, cid.Cid) (*DeleteResult, error)
+	Rename(context.Context, cid.Cid, string) (*RenameResult, error)
+	User(context.Context) (*User, error)
+	Tokens(context.Context) ([]Token, error)
 	Pin(context.Context, cid.Cid, ...PinOption) (*PinResponse, error)
+	PinAndWait(context.Context, cid.Cid, ...PinWaitOption) (*PinResponse, error)
+	ListPins(context.Context, ...ListPinsOption) (*PinIterator, error)
+	GetPin(context.Context, string) (*PinResponse, error)
+	ReplacePin(context.Context, string, cid.Cid, ...PinOption) (*PinResponse, error)
+	DeletePin(context.Context, string) error
+	Sync(context.Context, string, ...SyncOption) (*Snapshot, error)
+	Blockstore() blockstore.Blockstore
+	PutDag(context.Context, datamodel.Node, uint64, ...PutOption) (cid.Cid, error)
+	GetDag(context.Context, cid.Cid) (*w3http.Dag, error)
+	Close() error
 }
 
 type clientConfig struct {
-	token    string
-	endpoint string
-	ds       ds.Batching
-	hc       *http.Client
+	token       string
+	endpoint    string
+	ds          ds.Batching
+	hc          *http.Client
+	middlewares []Middleware
+	tp          trace.TracerProvider
+	mp          metric.MeterProvider
+	log         logging.Logger
+	limits      limitConfig
+	endpoints   map[string]limitConfig
+	retry       retryConfig
+	timeout     time.Duration
+	dsPath      string
+	cleanup     bool
+	cacheFirst  bool
+
+	gateways        []string
+	gatewayStrategy GatewayStrategy
+	gatewayMaxSize  int64
+	// gatewayHC sends requests to gateways without the middlewares, retries
+	// and limits of hc, which are for the API.
+	gatewayHC *http.Client
 }
 
 type client struct {
-	cfg  *clientConfig
+	cfg *clientConfig
+	tel *telemetry
+	// bs and bsvc are nil if no datastore was configured.
+	bs   blockstore.Blockstore
 	bsvc bserv.BlockService
+	refs blockRefs
+	// opened is the datastore opened by NewClient, which is closed by Close.
+	opened io.Closer
 }
 
 // NewClient creates a new web3.storage API client.
@@ -44,6 +90,9 @@ func NewClient(options ...Option) (Client, error) {
 	cfg := clientConfig{
 		endpoint: "https://api.web3.storage",
 		hc:       &http.Client{},
+		tp:       otel.GetTracerProvider(),
+		mp:       global.MeterProvider(),
+		log:      logging.Nop,
 	}
 	for _, opt := range options {
 		if err := opt(&cfg); err != nil {
@@ -53,14 +102,73 @@ func NewClient(options ...Option) (Client, error) {
 	if cfg.token == "" {
 		return nil, fmt.Errorf("missing auth token")
 	}
-	c := client{cfg: &cfg}
+	tel, err := newTelemetry(cfg.tp, cfg.mp)
+	if err != nil {
+		return nil, err
+	}
+	// Copy the HTTP client so the one passed in is not modified.
+	hc := *cfg.hc
+	rt := hc.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	ep, err := url.Parse(cfg.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parsing endpoint: %w", err)
+	}
+	gc := hc
+	gc.Transport = clientTransport{rt, cfg.log}
+	if cfg.timeout > 0 {
+		gc.Timeout = cfg.timeout
+	}
+	cfg.gatewayHC = &gc
+	rt = newLimitTransport(clientTransport{rt, cfg.log}, ep.Path, cfg.limits, cfg.endpoints)
+	rt = newRetryTransport(rt, ep.Path, cfg.retry, tel, cfg.log)
+	hc.Transport = chainMiddleware(rt, cfg.middlewares)
+	if cfg.timeout > 0 {
+		hc.Timeout = cfg.timeout
+	}
+	cfg.hc = &hc
+	c := client{cfg: &cfg, tel: tel, refs: blockRefs{refs: map[cid.Cid]int{}}}
+	if cfg.ds == nil && cfg.dsPath != "" {
+		lds, err := leveldb.NewDatastore(cfg.dsPath, nil)
+		if err != nil {
+			return nil, fmt.Errorf("opening datastore: %w", err)
+		}
+		cfg.ds = lds
+		c.opened = lds
+	}
 	if cfg.ds != nil {
-		c.bsvc = bserv.New(blockstore.NewBlockstore(cfg.ds), nil)
-	} else {
-		ds := dssync.MutexWrap(ds.NewMapDatastore())
-		c.bsvc = bserv.New(blockstore.NewBlockstore(ds), nil)
+		c.bs = blockstore.NewBlockstore(cfg.ds)
+		c.bsvc = bserv.New(c.bs, nil)
 	}
 	return &c, nil
 }
 
+// Close closes the datastore opened for WithDatastorePath. A datastore passed
+// with WithDatastore is left open.
+func (c *client) Close() error {
+	if c.opened == nil {
+		return nil
+	}
+	err := c.opened.Close()
+	c.opened = nil
+	if err != nil {
+		return fmt.Errorf("closing datastore: %w", err)
+	}
+	return nil
+}
+
+// newRequest creates a request to the API for the passed path, which must start
+// with "/", with the auth token and client headers set.
+func (c *client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.cfg.endpoint+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.cfg.token))
+	req.Header.Add("X-Client", clientName)
+	return req, nil
+}
+
 var _ Client = (*client)(nil)
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+
+	"github.com/ipfs/go-cid"
+	w3s "github.com/web3-storage/go-w3s-client"
+	"github.com/web3-storage/go-w3s-client/dagdiff"
+)
+
+type changeOutput struct {
+	Type       string `json:"type"`
+	Path       string `json:"path"`
+	IsDir      bool   `json:"isDir"`
+	Before     string `json:"before,omitempty"`
+	After      string `json:"after,omitempty"`
+	BeforeSize int64  `json:"beforeSize"`
+	AfterSize  int64  `json:"afterSize"`
+}
+
+func cidString(c cid.Cid) string {
+	if !c.Defined() {
+		return ""
+	}
+	return c.String()
+}
+
+func runDiff(ctx context.Context, g *globals, args []string) error {
+	flags := newFlagSet("diff", g)
+	args, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return errUsage
+	}
+	before, err := parseCid(args[:1])
+	if err != nil {
+		return err
+	}
+	after, err := parseCid(args[1:])
+	if err != nil {
+		return err
+	}
+
+	c, err := g.client()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	oldFs, err := getFiles(ctx, c, before)
+	if err != nil {
+		return err
+	}
+	newFs, err := getFiles(ctx, c, after)
+	if err != nil {
+		return err
+	}
+	changes, err := dagdiff.DiffFS(oldFs, newFs)
+	if err != nil {
+		return err
+	}
+
+	for _, ch := range changes {
+		out := changeOutput{
+			Type:       ch.Type.String(),
+			Path:       ch.Path,
+			IsDir:      ch.IsDir,
+			Before:     cidString(ch.Before),
+			After:      cidString(ch.After),
+			BeforeSize: ch.BeforeSize,
+			AfterSize:  ch.AfterSize,
+		}
+		err := g.print(out, "%s", formatChange(ch))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getFiles downloads the DAG with the passed root, returning its files.
+func getFiles(ctx context.Context, c w3s.Client, root cid.Cid) (fs.FS, error) {
+	res, err := c.Get(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected response status for %s: %d", root, res.StatusCode)
+	}
+	_, fsys, err := res.Files()
+	return fsys, err
+}
+
+func formatChange(c dagdiff.Change) string {
+	switch c.Type {
+	case dagdiff.Added:
+		if c.IsDir {
+			return fmt.Sprintf("A %s/", c.Path)
+		}
+		return fmt.Sprintf("A %s (%s)", c.Path, formatBytes(c.AfterSize))
+	case dagdiff.Removed:
+		if c.IsDir {
+			return fmt.Sprintf("D %s/", c.Path)
+		}
+		return fmt.Sprintf("D %s (%s)", c.Path, formatBytes(c.BeforeSize))
+	default:
+		sign := "+"
+		delta := c.Delta()
+		if delta < 0 {
+			sign, delta = "-", -delta
+		}
+		return fmt.Sprintf("M %s (%s%s)", c.Path, sign, formatBytes(delta))
+	}
+}
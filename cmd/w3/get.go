@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	w3s "github.com/web3-storage/go-w3s-client"
+	w3http "github.com/web3-storage/go-w3s-client/http"
+)
+
+func parseCid(args []string) (cid.Cid, error) {
+	if len(args) != 1 {
+		return cid.Undef, errUsage
+	}
+	c, err := cid.Parse(args[0])
+	if err != nil {
+		return cid.Undef, fmt.Errorf("invalid CID %q: %w", args[0], err)
+	}
+	return c, nil
+}
+
+type getOutput struct {
+	Cid   string `json:"cid"`
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes,omitempty"`
+}
+
+func runGet(ctx context.Context, g *globals, args []string) error {
+	flags := newFlagSet("get", g)
+	output := flags.String("o", "", "directory to extract to (default the CID)")
+	overwrite := flags.Bool("overwrite", false, "overwrite existing files when extracting")
+	carPath := flags.String("car", "", "write the CAR to this file instead of extracting, - for stdout")
+	index := flags.Bool("index", false, "write the CAR as an indexed CARv2 (requires -car with a file)")
+	args, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	root, err := parseCid(args)
+	if err != nil {
+		return err
+	}
+	if *index && (*carPath == "" || *carPath == "-") {
+		return errUsage
+	}
+
+	p := g.progress("Downloading", 0)
+	c, err := g.client(w3s.WithMiddleware(p.Middleware("GET", "/car/", false)))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	res, err := c.Get(ctx, root)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return fmt.Errorf("unexpected response status: %d", res.StatusCode)
+	}
+	p.SetTotal(res.ContentLength)
+
+	if *index {
+		err := res.SaveCar(*carPath)
+		p.Done()
+		if err != nil {
+			return err
+		}
+		return g.print(getOutput{Cid: root.String(), Path: *carPath}, "Saved %s to %s", root, *carPath)
+	}
+	if *carPath != "" {
+		var w io.Writer = g.stdout
+		if *carPath != "-" {
+			f, err := os.Create(*carPath)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		n, err := io.Copy(w, res.Body)
+		p.Done()
+		if err != nil {
+			return err
+		}
+		if *carPath == "-" {
+			return nil
+		}
+		return g.print(getOutput{root.String(), *carPath, n}, "Saved %s to %s (%s)", root, *carPath, formatBytes(n))
+	}
+
+	out := *output
+	if out == "" {
+		out = root.String()
+	}
+	policy := w3http.ConflictFail
+	if *overwrite {
+		policy = w3http.ConflictOverwrite
+	}
+	err = res.ExtractTo(out, w3http.WithConflictPolicy(policy))
+	p.Done()
+	if err != nil {
+		return err
+	}
+	return g.print(getOutput{Cid: root.String(), Path: out}, "Extracted %s to %s", root, out)
+}
+
+type statusOutput struct {
+	Cid     string       `json:"cid"`
+	Name    string       `json:"name,omitempty"`
+	DagSize uint64       `json:"dagSize"`
+	Created time.Time    `json:"created"`
+	Pins    []pinOutput  `json:"pins"`
+	Deals   []dealOutput `json:"deals"`
+}
+
+type pinOutput struct {
+	PeerID   string `json:"peerId"`
+	PeerName string `json:"peerName"`
+	Region   string `json:"region"`
+	Status   string `json:"status"`
+}
+
+type dealOutput struct {
+	DealID          uint64 `json:"dealId,omitempty"`
+	StorageProvider string `json:"storageProvider,omitempty"`
+	Status          string `json:"status"`
+}
+
+func newStatusOutput(s *w3s.Status) statusOutput {
+	out := statusOutput{
+		Cid:     s.Cid.String(),
+		Name:    s.Name,
+		DagSize: s.DagSize,
+		Created: s.Created,
+		Pins:    []pinOutput{},
+		Deals:   []dealOutput{},
+	}
+	for _, p := range s.Pins {
+		out.Pins = append(out.Pins, pinOutput{p.PeerID.String(), p.PeerName, p.Region, p.Status.String()})
+	}
+	for _, d := range s.Deals {
+		out.Deals = append(out.Deals, dealOutput{d.DealID, d.StorageProvider.String(), d.Status.String()})
+	}
+	return out
+}
+
+func runStatus(ctx context.Context, g *globals, args []string) error {
+	flags := newFlagSet("status", g)
+	args, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	root, err := parseCid(args)
+	if err != nil {
+		return err
+	}
+	c, err := g.client()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	s, err := c.Status(ctx, root)
+	if err != nil {
+		return err
+	}
+	out := newStatusOutput(s)
+	if g.json {
+		return g.print(out, "")
+	}
+
+	fmt.Fprintf(g.stdout, "CID:      %s\n", out.Cid)
+	if out.Name != "" {
+		fmt.Fprintf(g.stdout, "Name:     %s\n", out.Name)
+	}
+	fmt.Fprintf(g.stdout, "Size:     %s\n", formatBytes(int64(out.DagSize)))
+	fmt.Fprintf(g.stdout, "Created:  %s\n", out.Created.Format(time.RFC3339))
+	fmt.Fprintf(g.stdout, "Pins:\n")
+	for _, p := range out.Pins {
+		fmt.Fprintf(g.stdout, "  %s %s (%s) %s\n", p.PeerID, p.PeerName, p.Region, p.Status)
+	}
+	fmt.Fprintf(g.stdout, "Deals:\n")
+	for _, d := range out.Deals {
+		fmt.Fprintf(g.stdout, "  %d %s %s\n", d.DealID, d.StorageProvider, d.Status)
+	}
+	return nil
+}
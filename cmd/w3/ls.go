@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	w3s "github.com/web3-storage/go-w3s-client"
+)
+
+// timeFlag is a flag holding an RFC 3339 time.
+type timeFlag struct {
+	t time.Time
+}
+
+func (f *timeFlag) String() string {
+	if f.t.IsZero() {
+		return ""
+	}
+	return f.t.Format(time.RFC3339)
+}
+
+func (f *timeFlag) Set(s string) error {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return err
+	}
+	f.t = t
+	return nil
+}
+
+var _ flag.Value = (*timeFlag)(nil)
+
+type uploadOutput struct {
+	Cid     string    `json:"cid"`
+	Name    string    `json:"name,omitempty"`
+	DagSize uint64    `json:"dagSize"`
+	Created time.Time `json:"created"`
+}
+
+func runList(ctx context.Context, g *globals, args []string) error {
+	flags := newFlagSet("ls", g)
+	var before, after timeFlag
+	flags.Var(&before, "before", "list uploads created before this time (RFC 3339)")
+	flags.Var(&after, "after", "list uploads created after this time (RFC 3339)")
+	sortBy := flags.String("sort", "", "sort by date or name")
+	order := flags.String("order", "", "sort order, asc or desc")
+	name := flags.String("name", "", "only list uploads with this name")
+	max := flags.Int("max", 0, "maximum number of uploads to list")
+	cursor := flags.String("cursor", "", "resume listing from a cursor printed by a previous call")
+	args, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	var opts []w3s.ListOption
+	if !before.t.IsZero() {
+		opts = append(opts, w3s.WithBefore(before.t))
+	}
+	if !after.t.IsZero() {
+		opts = append(opts, w3s.WithAfter(after.t))
+	}
+	if *sortBy != "" {
+		opts = append(opts, w3s.WithSortBy(*sortBy))
+	}
+	if *order != "" {
+		opts = append(opts, w3s.WithSortOrder(*order))
+	}
+	if *name != "" {
+		opts = append(opts, w3s.WithNameFilter(*name))
+	}
+	if *max > 0 {
+		opts = append(opts, w3s.WithMaxResults(*max))
+	}
+	if *cursor != "" {
+		opts = append(opts, w3s.WithCursor(*cursor))
+	}
+
+	c, err := g.client()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	it, err := c.List(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for {
+		s, err := it.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if cur := it.Cursor(); cur != "" {
+				fmt.Fprintf(g.stderr, "resume with -cursor %s\n", cur)
+			}
+			return err
+		}
+		out := uploadOutput{s.Cid.String(), s.Name, s.DagSize, s.Created}
+		err = g.print(out, "%s\t%s\t%s\t%s", out.Cid, out.Created.Format(time.RFC3339), formatBytes(int64(out.DagSize)), out.Name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+// Command w3 is a command line tool for web3.storage.
+//
+// Usage:
+//
+//	w3 <command> [flags] [args]
+//
+// The auth token and endpoint are read from the W3S_TOKEN and W3S_ENDPOINT
+// environment variables, the config file named by W3S_CONFIG or the -config
+// flag, or the -token and -endpoint flags. Run "w3 help" for the list of
+// commands.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+
+	w3s "github.com/web3-storage/go-w3s-client"
+)
+
+// command is a w3 subcommand.
+type command struct {
+	usage string
+	short string
+	run   func(ctx context.Context, g *globals, args []string) error
+}
+
+var commands map[string]*command
+
+// Commands are registered in init as their functions refer back to the map.
+func init() {
+	commands = map[string]*command{
+		"put":     {usage: "put [flags] <path>...", short: "Upload files or directories", run: runPut},
+		"put-car": {usage: "put-car [flags] <file.car>", short: "Upload a CAR file", run: runPutCar},
+		"get":     {usage: "get [flags] <cid>", short: "Download content, extracting files or saving the CAR", run: runGet},
+		"status":  {usage: "status [flags] <cid>", short: "Show pin and deal status of an upload", run: runStatus},
+		"ls":      {usage: "ls [flags]", short: "List uploads", run: runList},
+		"pin":     {usage: "pin [flags] <cid>", short: "Pin a CID using the pinning service API", run: runPin},
+		"cid":     {usage: "cid [flags] <path>", short: "Compute the CID of files or directories without uploading", run: runCid},
+		"diff":    {usage: "diff [flags] <cid> <cid>", short: "Show files added, removed and modified between two uploads", run: runDiff},
+	}
+}
+
+// globals are the flags accepted by every command.
+type globals struct {
+	token    string
+	endpoint string
+	config   string
+	profile  string
+	json     bool
+	quiet    bool
+
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (g *globals) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.token, "token", "", "auth token (default $"+w3s.EnvToken+")")
+	fs.StringVar(&g.endpoint, "endpoint", "", "API endpoint (default $"+w3s.EnvEndpoint+")")
+	fs.StringVar(&g.config, "config", "", "config file (default $"+w3s.EnvConfigFile+")")
+	fs.StringVar(&g.profile, "profile", "", "config file profile (default $"+w3s.EnvProfile+")")
+	fs.BoolVar(&g.json, "json", false, "print output as JSON")
+	fs.BoolVar(&g.quiet, "quiet", false, "do not show progress")
+}
+
+// client creates an API client configured from the environment and flags.
+func (g *globals) client(options ...w3s.Option) (w3s.Client, error) {
+	var opts []w3s.Option
+	if g.config != "" {
+		profile := g.profile
+		if profile == "" {
+			profile = os.Getenv(w3s.EnvProfile)
+		}
+		opts = append(opts, w3s.WithConfigProfile(g.config, profile))
+	}
+	if g.token != "" {
+		opts = append(opts, w3s.WithToken(g.token))
+	}
+	if g.endpoint != "" {
+		opts = append(opts, w3s.WithEndpoint(g.endpoint))
+	}
+	opts = append(opts, options...)
+	return w3s.NewClientFromEnv(opts...)
+}
+
+// print writes v as JSON if the -json flag was passed, otherwise it writes
+// the text.
+func (g *globals) print(v interface{}, format string, args ...interface{}) error {
+	if g.json {
+		return json.NewEncoder(g.stdout).Encode(v)
+	}
+	_, err := fmt.Fprintf(g.stdout, format+"\n", args...)
+	return err
+}
+
+// progress returns a progress reporter, or nil if progress is disabled.
+func (g *globals) progress(label string, total int64) *progress {
+	if g.quiet || g.json || !isTerminal(g.stderr) {
+		return nil
+	}
+	return newProgress(g.stderr, label, total)
+}
+
+var (
+	// errUsage indicates the command was invoked with the wrong arguments.
+	errUsage = errors.New("usage")
+	// errFlags indicates the flags could not be parsed. The flag package
+	// has already reported the problem.
+	errFlags = errors.New("invalid flags")
+)
+
+// parseFlags parses the command flags, returning the remaining arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil, err
+		}
+		return nil, errFlags
+	}
+	return fs.Args(), nil
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: w3 <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].short)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "w3 <command> -h" for command flags.`)
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stderr)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "w3: unknown command %q\n\n", args[0])
+		usage(stderr)
+		return 2
+	}
+	g := globals{stdout: stdout, stderr: stderr}
+	err := cmd.run(ctx, &g, args[1:])
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err == errUsage {
+		fmt.Fprintf(stderr, "Usage: w3 %s\n", cmd.usage)
+		return 2
+	}
+	if err == errFlags {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "w3 %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// newFlagSet creates a flag set for a command with the global flags
+// registered.
+func newFlagSet(name string, g *globals) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(g.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(g.stderr, "Usage: w3 %s\n\nFlags:\n", commands[name].usage)
+		fs.PrintDefaults()
+	}
+	g.register(fs)
+	return fs
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
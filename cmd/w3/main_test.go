@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/web3-storage/go-w3s-client/w3stest"
+)
+
+func runW3(t *testing.T, srv *w3stest.Server, args ...string) string {
+	var stdout, stderr bytes.Buffer
+	args = append(args[:1:1], append([]string{"-endpoint", srv.URL, "-token", "secret"}, args[1:]...)...)
+	if code := run(context.Background(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("w3 %s: exit code %d: %s", strings.Join(args, " "), code, stderr.String())
+	}
+	return stdout.String()
+}
+
+func TestPutGetRoundTrip(t *testing.T) {
+	srv := w3stest.NewServer()
+	defer srv.Close()
+
+	dir := filepath.Join(t.TempDir(), "site")
+	if err := os.MkdirAll(filepath.Join(dir, "css"), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	files := map[string]string{
+		"index.html":    "<h1>hello</h1>",
+		"css/style.css": "h1 { color: red }",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	var put, offline cidOutput
+	if err := json.Unmarshal([]byte(runW3(t, srv, "put", "-json", "-name", "site", dir)), &put); err != nil {
+		t.Fatalf("failed to decode put output: %v", err)
+	}
+	if err := json.Unmarshal([]byte(runW3(t, srv, "cid", "-json", dir)), &offline); err != nil {
+		t.Fatalf("failed to decode cid output: %v", err)
+	}
+	if put.Cid != offline.Cid {
+		t.Fatalf("got offline cid %s, wanted %s", offline.Cid, put.Cid)
+	}
+
+	var status statusOutput
+	if err := json.Unmarshal([]byte(runW3(t, srv, "status", "-json", put.Cid)), &status); err != nil {
+		t.Fatalf("failed to decode status output: %v", err)
+	}
+	if status.Name != "site" {
+		t.Fatalf("got name %s, wanted %s", status.Name, "site")
+	}
+
+	ls := runW3(t, srv, "ls")
+	if !strings.HasPrefix(ls, put.Cid+"\t") {
+		t.Fatalf("got ls output %q, wanted a line for %s", ls, put.Cid)
+	}
+
+	out := filepath.Join(t.TempDir(), "out")
+	runW3(t, srv, "get", "-o", out, put.Cid)
+	for name, content := range files {
+		b, err := ioutil.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("failed to read extracted file: %v", err)
+		}
+		if string(b) != content {
+			t.Fatalf("got content %q for %s, wanted %q", b, name, content)
+		}
+	}
+
+	// An indexed CARv2 can be uploaded again as is.
+	carPath := filepath.Join(t.TempDir(), "site.car")
+	runW3(t, srv, "get", "-car", carPath, "-index", put.Cid)
+	if got := strings.TrimSpace(runW3(t, srv, "put-car", carPath)); got != put.Cid {
+		t.Fatalf("got cid %s from put-car, wanted %s", got, put.Cid)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	srv := w3stest.NewServer()
+	defer srv.Close()
+
+	dir := filepath.Join(t.TempDir(), "docs")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	write := func(name, content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	write("a.txt", "a")
+	write("b.txt", "b")
+	before := strings.TrimSpace(runW3(t, srv, "put", dir))
+	write("b.txt", "bb")
+	write("c.txt", "c")
+	after := strings.TrimSpace(runW3(t, srv, "put", dir))
+
+	out := runW3(t, srv, "diff", before, after)
+	wanted := "M /b.txt (+1 B)\nA /c.txt (1 B)\n"
+	if out != wanted {
+		t.Fatalf("got diff output %q, wanted %q", out, wanted)
+	}
+}
+
+func TestPinWait(t *testing.T) {
+	srv := w3stest.NewServer()
+	defer srv.Close()
+
+	var pin pinRequestOutput
+	out := runW3(t, srv, "pin", "-json", "-wait", "-poll-interval", "1ms", "-name", "hello", "bafybeicymili4gmgoa4xpx5jfghi7leffvai4fd47f6nxgrhq4ug6ekiga")
+	if err := json.Unmarshal([]byte(out), &pin); err != nil {
+		t.Fatalf("failed to decode pin output: %v", err)
+	}
+	if pin.Status != w3stest.PinPinned {
+		t.Fatalf("got status %s, wanted %s", pin.Status, w3stest.PinPinned)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"frobnicate"}, &stdout, &stderr); code != 2 {
+		t.Fatalf("got exit code %d, wanted %d", code, 2)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	w3s "github.com/web3-storage/go-w3s-client"
+)
+
+// stringsFlag is a flag that may be repeated.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+type pinRequestOutput struct {
+	RequestID string    `json:"requestId"`
+	Status    string    `json:"status"`
+	Cid       string    `json:"cid"`
+	Name      string    `json:"name,omitempty"`
+	Created   time.Time `json:"created"`
+}
+
+func runPin(ctx context.Context, g *globals, args []string) error {
+	flags := newFlagSet("pin", g)
+	name := flags.String("name", "", "name of the pin")
+	var origins, meta stringsFlag
+	flags.Var(&origins, "origin", "multiaddr of a peer providing the content (may be repeated)")
+	flags.Var(&meta, "meta", "metadata as key=value (may be repeated)")
+	wait := flags.Bool("wait", false, "wait until the content is pinned")
+	timeout := flags.Duration("timeout", 0, "maximum time to wait with -wait")
+	poll := flags.Duration("poll-interval", 0, "initial interval between status checks with -wait")
+	args, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	root, err := parseCid(args)
+	if err != nil {
+		return err
+	}
+
+	var opts []w3s.PinOption
+	if *name != "" {
+		opts = append(opts, w3s.WithPinName(*name))
+	}
+	for _, o := range origins {
+		opts = append(opts, w3s.WithPinOrigin(o))
+	}
+	for _, m := range meta {
+		kv := strings.SplitN(m, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid meta %q, expected key=value", m)
+		}
+		opts = append(opts, w3s.WithPinMeta(kv[0], kv[1]))
+	}
+
+	c, err := g.client()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	var res *w3s.PinResponse
+	if *wait {
+		var wopts []w3s.PinWaitOption
+		for _, o := range opts {
+			wopts = append(wopts, o)
+		}
+		if *timeout > 0 {
+			wopts = append(wopts, w3s.WithPinWaitTimeout(*timeout))
+		}
+		if *poll > 0 {
+			wopts = append(wopts, w3s.WithPinPollInterval(*poll, 30*(*poll)))
+		}
+		if !g.quiet && !g.json {
+			wopts = append(wopts, w3s.WithPinProgress(func(p *w3s.PinResponse) {
+				fmt.Fprintf(g.stderr, "%s: %s\n", p.RequestID, p.Status)
+			}))
+		}
+		res, err = c.PinAndWait(ctx, root, wopts...)
+	} else {
+		res, err = c.Pin(ctx, root, opts...)
+	}
+	if err != nil {
+		return err
+	}
+	out := pinRequestOutput{res.RequestID, string(res.Status), res.Pin.Cid.String(), res.Pin.Name, res.Created}
+	return g.print(out, "%s\t%s\t%s", out.RequestID, out.Status, out.Cid)
+}
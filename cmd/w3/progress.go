@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	w3s "github.com/web3-storage/go-w3s-client"
+)
+
+const progressInterval = 100 * time.Millisecond
+
+// progress draws a progress bar, or a byte counter when the total is unknown,
+// on a terminal. A nil *progress is valid and draws nothing.
+type progress struct {
+	w     io.Writer
+	label string
+	total int64
+	start time.Time
+
+	mu    sync.Mutex
+	n     int64
+	drawn time.Time
+}
+
+func newProgress(w io.Writer, label string, total int64) *progress {
+	return &progress{w: w, label: label, total: total, start: time.Now()}
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// SetTotal sets the total number of bytes expected, if known.
+func (p *progress) SetTotal(total int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total = total
+}
+
+// Add records n more bytes transferred.
+func (p *progress) Add(n int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.n += int64(n)
+	if time.Since(p.drawn) >= progressInterval {
+		p.draw()
+	}
+}
+
+// Done draws the final state and ends the line.
+func (p *progress) Done() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.draw()
+	fmt.Fprintln(p.w)
+}
+
+// draw must be called with the lock held.
+func (p *progress) draw() {
+	p.drawn = time.Now()
+	rate := ""
+	if secs := time.Since(p.start).Seconds(); secs > 0 {
+		rate = fmt.Sprintf(" %s/s", formatBytes(int64(float64(p.n)/secs)))
+	}
+	if p.total <= 0 {
+		fmt.Fprintf(p.w, "\r%s %s%s   ", p.label, formatBytes(p.n), rate)
+		return
+	}
+	const width = 30
+	done := int(p.n * width / p.total)
+	if done > width {
+		done = width
+	}
+	bar := strings.Repeat("=", done) + strings.Repeat(" ", width-done)
+	fmt.Fprintf(p.w, "\r%s [%s] %s / %s%s   ", p.label, bar, formatBytes(p.n), formatBytes(p.total), rate)
+}
+
+// Reader returns a reader that records bytes read from r.
+func (p *progress) Reader(r io.Reader) io.Reader {
+	if p == nil {
+		return r
+	}
+	return progressReader{r, p}
+}
+
+// Middleware returns client middleware recording the bytes sent in request
+// bodies (upload) or received in response bodies (download) for requests with
+// the passed method and path prefix.
+func (p *progress) Middleware(method, prefix string, upload bool) w3s.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return w3s.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if p == nil || req.Method != method || !strings.HasPrefix(req.URL.Path, prefix) {
+				return next.RoundTrip(req)
+			}
+			if upload && req.Body != nil {
+				req = req.Clone(req.Context())
+				req.Body = progressReadCloser{req.Body, p}
+			}
+			res, err := next.RoundTrip(req)
+			if err == nil && !upload {
+				res.Body = progressReadCloser{res.Body, p}
+			}
+			return res, err
+		})
+	}
+}
+
+type progressReader struct {
+	r io.Reader
+	p *progress
+}
+
+func (pr progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	pr.p.Add(n)
+	return n, err
+}
+
+type progressReadCloser struct {
+	io.ReadCloser
+	p *progress
+}
+
+func (pr progressReadCloser) Read(b []byte) (int, error) {
+	n, err := pr.ReadCloser.Read(b)
+	pr.p.Add(n)
+	return n, err
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	bserv "github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/ipfs/go-merkledag"
+	w3s "github.com/web3-storage/go-w3s-client"
+	"github.com/web3-storage/go-w3s-client/adder"
+	w3fs "github.com/web3-storage/go-w3s-client/fs"
+)
+
+type cidOutput struct {
+	Cid string `json:"cid"`
+}
+
+// openPaths opens the files to upload. A single path is opened as is, with the
+// directory containing it returned as the dirname used to read nested files.
+// Several paths must be regular files and are wrapped in a directory.
+func openPaths(paths []string) (fs.File, string, func(), error) {
+	if len(paths) == 1 {
+		f, err := os.Open(paths[0])
+		if err != nil {
+			return nil, "", nil, err
+		}
+		return f, filepath.Dir(paths[0]), func() { f.Close() }, nil
+	}
+
+	var files []fs.File
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			closeAll()
+			return nil, "", nil, err
+		}
+		files = append(files, f)
+		fi, err := f.Stat()
+		if err != nil {
+			closeAll()
+			return nil, "", nil, err
+		}
+		if fi.IsDir() {
+			closeAll()
+			return nil, "", nil, fmt.Errorf("%s is a directory, only a single directory can be uploaded", p)
+		}
+	}
+	return w3fs.NewDir("upload", files), "", closeAll, nil
+}
+
+func runPut(ctx context.Context, g *globals, args []string) error {
+	flags := newFlagSet("put", g)
+	name := flags.String("name", "", "name of the upload")
+	quota := flags.Bool("quota-check", false, "check the upload fits in the remaining storage before sending")
+	paths, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errUsage
+	}
+
+	file, dirname, closeFiles, err := openPaths(paths)
+	if err != nil {
+		return err
+	}
+	defer closeFiles()
+
+	p := g.progress("Uploading", 0)
+	c, err := g.client(w3s.WithMiddleware(p.Middleware("POST", "/car", true)))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	opts := []w3s.PutOption{w3s.WithDirname(dirname)}
+	if *name != "" {
+		opts = append(opts, w3s.WithName(*name))
+	}
+	if *quota {
+		opts = append(opts, w3s.WithQuotaCheck())
+	}
+	roots, err := c.Put(ctx, file, opts...)
+	p.Done()
+	if err != nil {
+		return err
+	}
+	return g.print(cidOutput{roots[0].String()}, "%s", roots[0])
+}
+
+func runPutCar(ctx context.Context, g *globals, args []string) error {
+	flags := newFlagSet("put-car", g)
+	name := flags.String("name", "", "name of the upload")
+	paths, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) != 1 {
+		return errUsage
+	}
+
+	f, err := os.Open(paths[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	p := g.progress("Uploading", fi.Size())
+	c, err := g.client(w3s.WithMiddleware(p.Middleware("POST", "/car", true)))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	var opts []w3s.PutOption
+	if *name != "" {
+		opts = append(opts, w3s.WithName(*name))
+	}
+	roots, err := c.PutCar(ctx, f, opts...)
+	p.Done()
+	if err != nil {
+		return err
+	}
+	for _, root := range roots {
+		if err := g.print(cidOutput{root.String()}, "%s", root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runCid(ctx context.Context, g *globals, args []string) error {
+	flags := newFlagSet("cid", g)
+	paths, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errUsage
+	}
+
+	file, dirname, closeFiles, err := openPaths(paths)
+	if err != nil {
+		return err
+	}
+	defer closeFiles()
+
+	root, err := computeCid(ctx, file, dirname)
+	if err != nil {
+		return err
+	}
+	return g.print(cidOutput{root.String()}, "%s", root)
+}
+
+// computeCid imports the file into an in-memory DAG in the same way as Put,
+// returning the root CID.
+func computeCid(ctx context.Context, file fs.File, dirname string) (cid.Cid, error) {
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	dag := merkledag.NewDAGService(bserv.New(bs, nil))
+	a, err := adder.NewAdder(ctx, dag)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return a.Import(file, dirname, nil)
+}
@@ -0,0 +1,337 @@
+package w3s
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/time/rate"
+)
+
+// Environment variables read by NewClientFromEnv.
+const (
+	EnvToken                 = "W3S_TOKEN"
+	EnvEndpoint              = "W3S_ENDPOINT"
+	EnvConfigFile            = "W3S_CONFIG"
+	EnvProfile               = "W3S_PROFILE"
+	EnvTimeout               = "W3S_TIMEOUT"
+	EnvMaxRetries            = "W3S_MAX_RETRIES"
+	EnvRetryMinBackoff       = "W3S_RETRY_MIN_BACKOFF"
+	EnvRetryMaxBackoff       = "W3S_RETRY_MAX_BACKOFF"
+	EnvDatastore             = "W3S_DATASTORE"
+	EnvMaxConcurrentRequests = "W3S_MAX_CONCURRENT_REQUESTS"
+	EnvRateLimit             = "W3S_RATE_LIMIT"
+	EnvRateBurst             = "W3S_RATE_BURST"
+	// EnvGateways is a comma separated list of gateway URLs.
+	EnvGateways = "W3S_GATEWAYS"
+)
+
+// duration is a time.Duration read from a string such as "30s".
+type duration time.Duration
+
+func (d *duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+// fileConfig is the client configuration read from a config file or the
+// environment. Fields where zero is a meaningful setting, such as a max_retries
+// of 0 to turn off retries set for all profiles, are pointers so they are
+// applied when set to zero. Other zero values are not applied.
+type fileConfig struct {
+	Token                 string    `json:"token" toml:"token"`
+	Endpoint              string    `json:"endpoint" toml:"endpoint"`
+	Timeout               *duration `json:"timeout" toml:"timeout"`
+	MaxRetries            *int      `json:"max_retries" toml:"max_retries"`
+	RetryMinBackoff       duration  `json:"retry_min_backoff" toml:"retry_min_backoff"`
+	RetryMaxBackoff       duration  `json:"retry_max_backoff" toml:"retry_max_backoff"`
+	Datastore             *string   `json:"datastore" toml:"datastore"`
+	MaxConcurrentRequests *int      `json:"max_concurrent_requests" toml:"max_concurrent_requests"`
+	RateLimit             *float64  `json:"rate_limit" toml:"rate_limit"`
+	RateBurst             int       `json:"rate_burst" toml:"rate_burst"`
+	Gateways              []string  `json:"gateways" toml:"gateways"`
+}
+
+// merge overrides the values of c with the values set in o.
+func (c *fileConfig) merge(o fileConfig) {
+	if o.Token != "" {
+		c.Token = o.Token
+	}
+	if o.Endpoint != "" {
+		c.Endpoint = o.Endpoint
+	}
+	if o.Timeout != nil {
+		c.Timeout = o.Timeout
+	}
+	if o.MaxRetries != nil {
+		c.MaxRetries = o.MaxRetries
+	}
+	if o.RetryMinBackoff != 0 {
+		c.RetryMinBackoff = o.RetryMinBackoff
+	}
+	if o.RetryMaxBackoff != 0 {
+		c.RetryMaxBackoff = o.RetryMaxBackoff
+	}
+	if o.Datastore != nil {
+		c.Datastore = o.Datastore
+	}
+	if o.MaxConcurrentRequests != nil {
+		c.MaxConcurrentRequests = o.MaxConcurrentRequests
+	}
+	if o.RateLimit != nil {
+		c.RateLimit = o.RateLimit
+	}
+	if o.RateBurst != 0 {
+		c.RateBurst = o.RateBurst
+	}
+	if o.Gateways != nil {
+		c.Gateways = o.Gateways
+	}
+}
+
+// apply applies the config to the client config.
+func (c *fileConfig) apply(cfg *clientConfig) error {
+	var opts []Option
+	if c.Token != "" {
+		opts = append(opts, WithToken(c.Token))
+	}
+	if c.Endpoint != "" {
+		opts = append(opts, WithEndpoint(c.Endpoint))
+	}
+	if c.Timeout != nil {
+		opts = append(opts, WithTimeout(time.Duration(*c.Timeout)))
+	}
+	if c.MaxRetries != nil {
+		opts = append(opts, WithMaxRetries(*c.MaxRetries))
+	}
+	if c.RetryMinBackoff != 0 || c.RetryMaxBackoff != 0 {
+		min, max := time.Duration(c.RetryMinBackoff), time.Duration(c.RetryMaxBackoff)
+		if min == 0 {
+			min = defaultRetryMinBackoff
+		}
+		if max == 0 {
+			max = defaultRetryMaxBackoff
+		}
+		opts = append(opts, WithRetryBackoff(min, max))
+	}
+	if c.Datastore != nil {
+		opts = append(opts, WithDatastorePath(*c.Datastore))
+	}
+	if c.MaxConcurrentRequests != nil {
+		if *c.MaxConcurrentRequests == 0 {
+			// Zero removes a limit set for all profiles.
+			cfg.limits.concurrency = 0
+		} else {
+			opts = append(opts, WithMaxConcurrentRequests(*c.MaxConcurrentRequests))
+		}
+	}
+	if c.RateLimit != nil {
+		if *c.RateLimit == 0 {
+			cfg.limits.rate = 0
+			cfg.limits.burst = 0
+		} else {
+			opts = append(opts, WithRateLimit(rate.Limit(*c.RateLimit), c.RateBurst))
+		}
+	}
+	if c.Gateways != nil {
+		cfg.gateways = nil
+		opts = append(opts, WithGateways(c.Gateways...))
+	}
+	for _, opt := range opts {
+		if err := opt(cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configFile is the format of a config file. Values at the top level apply to
+// every profile and are overridden by the values of the selected profile.
+type configFile struct {
+	fileConfig
+	// Profile is the name of the profile used if none is selected.
+	Profile  string                `json:"profile" toml:"profile"`
+	Profiles map[string]fileConfig `json:"profiles" toml:"profiles"`
+}
+
+// readConfigFile reads the config for the named profile from a TOML or JSON
+// file. The format is determined by the file extension, ".json" for JSON and
+// TOML otherwise.
+func readConfigFile(path, profile string) (*fileConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	var f configFile
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(b, &f)
+	} else {
+		err = toml.Unmarshal(b, &f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	if profile == "" {
+		profile = f.Profile
+	}
+	cfg := f.fileConfig
+	if profile != "" {
+		p, ok := f.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("profile %q not found in config file %s", profile, path)
+		}
+		cfg.merge(p)
+	}
+	return &cfg, nil
+}
+
+// WithConfigFile reads client configuration from a TOML or JSON file (chosen by
+// the ".json" extension). The profile is taken from the W3S_PROFILE environment
+// variable, or the "profile" key of the file. Options passed after this one
+// override values read from the file.
+//
+// An example TOML file:
+//
+//	endpoint = "https://api.web3.storage"
+//	max_retries = 3
+//	gateways = ["https://dweb.link"]
+//	profile = "personal"
+//
+//	[profiles.personal]
+//	token = "..."
+//
+//	[profiles.work]
+//	token = "..."
+//	timeout = "1m"
+//	datastore = "/var/lib/w3s"
+func WithConfigFile(path string) Option {
+	return WithConfigProfile(path, os.Getenv(EnvProfile))
+}
+
+// WithConfigProfile reads client configuration for the named profile from a
+// TOML or JSON file. See WithConfigFile for the file format.
+func WithConfigProfile(path, profile string) Option {
+	return func(cfg *clientConfig) error {
+		fc, err := readConfigFile(path, profile)
+		if err != nil {
+			return err
+		}
+		return fc.apply(cfg)
+	}
+}
+
+// envConfig reads client configuration from W3S_* environment variables.
+func envConfig() (*fileConfig, error) {
+	var c fileConfig
+	c.Token = os.Getenv(EnvToken)
+	c.Endpoint = os.Getenv(EnvEndpoint)
+	if v := os.Getenv(EnvDatastore); v != "" {
+		c.Datastore = &v
+	}
+	if v := os.Getenv(EnvGateways); v != "" {
+		c.Gateways = []string{}
+		for _, gw := range strings.Split(v, ",") {
+			if gw = strings.TrimSpace(gw); gw != "" {
+				c.Gateways = append(c.Gateways, gw)
+			}
+		}
+	}
+	var err error
+	if c.Timeout, err = envDuration(EnvTimeout); err != nil {
+		return nil, err
+	}
+	for name, d := range map[string]*duration{
+		EnvRetryMinBackoff: &c.RetryMinBackoff,
+		EnvRetryMaxBackoff: &c.RetryMaxBackoff,
+	} {
+		v, err := envDuration(name)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			*d = *v
+		}
+	}
+	if c.MaxRetries, err = envInt(EnvMaxRetries); err != nil {
+		return nil, err
+	}
+	if c.MaxConcurrentRequests, err = envInt(EnvMaxConcurrentRequests); err != nil {
+		return nil, err
+	}
+	burst, err := envInt(EnvRateBurst)
+	if err != nil {
+		return nil, err
+	}
+	if burst != nil {
+		c.RateBurst = *burst
+	}
+	if v := os.Getenv(EnvRateLimit); v != "" {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", EnvRateLimit, err)
+		}
+		c.RateLimit = &r
+	}
+	return &c, nil
+}
+
+// envDuration returns the duration in the named environment variable, or nil
+// if it is not set.
+func envDuration(name string) (*duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil, nil
+	}
+	var d duration
+	if err := d.UnmarshalText([]byte(v)); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", name, err)
+	}
+	return &d, nil
+}
+
+// envInt returns the integer in the named environment variable, or nil if it
+// is not set.
+func envInt(name string) (*int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", name, err)
+	}
+	return &i, nil
+}
+
+// NewClientFromEnv creates a new web3.storage API client configured from the
+// environment. If W3S_CONFIG is set the config file it names is read first,
+// using the profile named by W3S_PROFILE (see WithConfigFile), then values are
+// read from the W3S_TOKEN, W3S_ENDPOINT, W3S_TIMEOUT, W3S_MAX_RETRIES,
+// W3S_RETRY_MIN_BACKOFF, W3S_RETRY_MAX_BACKOFF, W3S_DATASTORE,
+// W3S_MAX_CONCURRENT_REQUESTS, W3S_RATE_LIMIT, W3S_RATE_BURST and W3S_GATEWAYS
+// (a comma separated list of gateway URLs) variables. The passed options
+// override values from the environment.
+func NewClientFromEnv(options ...Option) (Client, error) {
+	var opts []Option
+	if path := os.Getenv(EnvConfigFile); path != "" {
+		opts = append(opts, WithConfigFile(path))
+	}
+	ec, err := envConfig()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, ec.apply)
+	opts = append(opts, options...)
+	return NewClient(opts...)
+}
@@ -0,0 +1,149 @@
+package w3s
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const tomlConfig = `
+endpoint = "https://example.com"
+max_retries = 2
+profile = "personal"
+
+[profiles.personal]
+token = "personal-token"
+
+[profiles.work]
+token = "work-token"
+timeout = "1m"
+max_concurrent_requests = 4
+
+[profiles.noretry]
+token = "noretry-token"
+max_retries = 0
+`
+
+const jsonConfig = `{
+	"endpoint": "https://example.com",
+	"profiles": {
+		"work": {"token": "work-token", "retry_min_backoff": "10ms", "retry_max_backoff": "1s"}
+	}
+}`
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func clientConfigOf(t *testing.T, c Client, err error) *clientConfig {
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return c.(*client).cfg
+}
+
+func TestConfigFileProfiles(t *testing.T) {
+	path := writeConfig(t, "config.toml", tomlConfig)
+
+	c, err := NewClient(WithConfigFile(path))
+	cfg := clientConfigOf(t, c, err)
+	if cfg.token != "personal-token" {
+		t.Fatalf("got token %s, wanted %s", cfg.token, "personal-token")
+	}
+	if cfg.endpoint != "https://example.com" {
+		t.Fatalf("got endpoint %s, wanted %s", cfg.endpoint, "https://example.com")
+	}
+	if cfg.retry.max != 2 {
+		t.Fatalf("got max retries %d, wanted %d", cfg.retry.max, 2)
+	}
+
+	c, err = NewClient(WithConfigProfile(path, "work"), WithMaxRetries(5))
+	cfg = clientConfigOf(t, c, err)
+	if cfg.token != "work-token" {
+		t.Fatalf("got token %s, wanted %s", cfg.token, "work-token")
+	}
+	if cfg.timeout != time.Minute {
+		t.Fatalf("got timeout %v, wanted %v", cfg.timeout, time.Minute)
+	}
+	if cfg.limits.concurrency != 4 {
+		t.Fatalf("got max concurrent requests %d, wanted %d", cfg.limits.concurrency, 4)
+	}
+	if cfg.retry.max != 5 {
+		t.Fatalf("got max retries %d, wanted %d", cfg.retry.max, 5)
+	}
+
+	// A zero value in a profile overrides the top level value.
+	c, err = NewClient(WithConfigProfile(path, "noretry"))
+	cfg = clientConfigOf(t, c, err)
+	if cfg.retry.max != 0 {
+		t.Fatalf("got max retries %d, wanted %d", cfg.retry.max, 0)
+	}
+
+	_, err = NewClient(WithConfigProfile(path, "missing"))
+	if err == nil {
+		t.Fatalf("got nil error, wanted missing profile error")
+	}
+}
+
+func TestJSONConfigFile(t *testing.T) {
+	path := writeConfig(t, "config.json", jsonConfig)
+
+	c, err := NewClient(WithConfigProfile(path, "work"))
+	cfg := clientConfigOf(t, c, err)
+	if cfg.token != "work-token" {
+		t.Fatalf("got token %s, wanted %s", cfg.token, "work-token")
+	}
+	if cfg.retry.minBackoff != 10*time.Millisecond || cfg.retry.maxBackoff != time.Second {
+		t.Fatalf("got retry backoff %v to %v, wanted %v to %v", cfg.retry.minBackoff, cfg.retry.maxBackoff, 10*time.Millisecond, time.Second)
+	}
+}
+
+func setenv(t *testing.T, key, value string) {
+	prev, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	path := writeConfig(t, "config.toml", tomlConfig)
+	setenv(t, EnvConfigFile, path)
+	setenv(t, EnvProfile, "work")
+	setenv(t, EnvEndpoint, "https://env.example.com")
+	setenv(t, EnvMaxRetries, "7")
+
+	c, err := NewClientFromEnv(WithEndpoint("https://option.example.com"))
+	cfg := clientConfigOf(t, c, err)
+	if cfg.token != "work-token" {
+		t.Fatalf("got token %s, wanted %s", cfg.token, "work-token")
+	}
+	if cfg.retry.max != 7 {
+		t.Fatalf("got max retries %d, wanted %d", cfg.retry.max, 7)
+	}
+	if cfg.endpoint != "https://option.example.com" {
+		t.Fatalf("got endpoint %s, wanted %s", cfg.endpoint, "https://option.example.com")
+	}
+
+	setenv(t, EnvMaxRetries, "0")
+	c, err = NewClientFromEnv()
+	cfg = clientConfigOf(t, c, err)
+	if cfg.retry.max != 0 {
+		t.Fatalf("got max retries %d, wanted %d", cfg.retry.max, 0)
+	}
+
+	setenv(t, EnvTimeout, "soon")
+	if _, err := NewClientFromEnv(); err == nil {
+		t.Fatalf("got nil error, wanted invalid timeout error")
+	}
+}
@@ -0,0 +1,103 @@
+package w3s
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-merkledag"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/linking"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
+	w3http "github.com/web3-storage/go-w3s-client/http"
+	"go.opentelemetry.io/otel/attribute"
+
+	// Register the codecs structured data is encoded with.
+	_ "github.com/ipld/go-ipld-prime/codec/dagcbor"
+	_ "github.com/ipld/go-ipld-prime/codec/dagjson"
+	_ "github.com/ipld/go-ipld-prime/codec/raw"
+)
+
+// PutDag encodes the node as a block with the passed codec, one of
+// cid.DagCBOR, cid.DagJSON or cid.Raw, and uploads it, returning its CID.
+// Blocks the node links to that are held in the client's blockstore (see
+// WithDatastore) are uploaded with it, and any others are assumed to have
+// been uploaded already. Options that configure how files are read (WithFs,
+// WithDirname and WithFiles) and WithQuotaCheck are ignored.
+func (c *client) PutDag(ctx context.Context, node datamodel.Node, codec uint64, options ...PutOption) (root cid.Cid, err error) {
+	ctx, span := c.startSpan(ctx, "PutDag", attribute.Int64("codec", int64(codec)))
+	defer func() { endSpan(span, err) }()
+
+	var cfg putConfig
+	for _, opt := range options {
+		if err := opt(&cfg); err != nil {
+			return cid.Undef, err
+		}
+	}
+	switch codec {
+	case cid.DagCBOR, cid.DagJSON, cid.Raw:
+	default:
+		return cid.Undef, fmt.Errorf("unsupported codec: 0x%x", codec)
+	}
+
+	bsvc := c.blockService()
+	ls := cidlink.DefaultLinkSystem()
+	ls.StorageWriteOpener = func(lctx linking.LinkContext) (io.Writer, linking.BlockWriteCommitter, error) {
+		var buf bytes.Buffer
+		return &buf, func(lnk datamodel.Link) error {
+			b, err := blocks.NewBlockWithCid(buf.Bytes(), lnk.(cidlink.Link).Cid)
+			if err != nil {
+				return err
+			}
+			return bsvc.AddBlock(lctx.Ctx, b)
+		}, nil
+	}
+	lp := cidlink.LinkPrototype{Prefix: cid.Prefix{
+		Version:  1,
+		Codec:    codec,
+		MhType:   multihash.SHA2_256,
+		MhLength: -1,
+	}}
+	lnk, err := ls.Store(linking.LinkContext{Ctx: ctx}, lp, node)
+	if err != nil {
+		return cid.Undef, fmt.Errorf("encoding node: %w", err)
+	}
+	root = lnk.(cidlink.Link).Cid
+	span.SetAttributes(attribute.String("cid", root.String()))
+
+	// carbites cannot decode DAG-JSON, and linked blocks may not be held
+	// locally, so the DAG is packed into shards as it is walked.
+	bs := bsvc.Blockstore()
+	uploaded := func(c cid.Cid) (bool, error) {
+		has, err := bs.Has(ctx, c)
+		if err != nil {
+			return false, fmt.Errorf("checking for block %s: %w", c, err)
+		}
+		return !has, nil
+	}
+	_, err = c.putDelta(ctx, merkledag.NewDAGService(bsvc), []cid.Cid{root}, uploaded, cfg.name)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return root, nil
+}
+
+// GetDag retrieves the DAG with the passed root, which may use any codec,
+// decoding its nodes as they are loaded. It fails with a ResponseError if the
+// API does not return the content.
+func (c *client) GetDag(ctx context.Context, root cid.Cid) (*w3http.Dag, error) {
+	res, err := c.Get(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		return nil, newResponseError(res.Response)
+	}
+	return res.Dag()
+}
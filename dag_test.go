@@ -0,0 +1,150 @@
+package w3s
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/traversal"
+	w3http "github.com/web3-storage/go-w3s-client/http"
+	"github.com/web3-storage/go-w3s-client/w3stest"
+)
+
+func TestPutGetDag(t *testing.T) {
+	srv := w3stest.NewServer(w3stest.WithToken(validToken))
+	defer srv.Close()
+	ctx := context.Background()
+	client, err := NewClient(WithEndpoint(srv.URL), WithToken(validToken), WithDatastore(dssync.MutexWrap(ds.NewMapDatastore())))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	file := putFakeFile(t, client, "hello.txt", "hello")
+	child, err := qp.BuildMap(basicnode.Prototype.Any, 1, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "name", qp.String("child"))
+	})
+	if err != nil {
+		t.Fatalf("failed to build node: %v", err)
+	}
+	childCid, err := client.PutDag(ctx, child, cid.DagJSON)
+	if err != nil {
+		t.Fatalf("failed to put child: %v", err)
+	}
+	if got := childCid.Prefix().Codec; got != cid.DagJSON {
+		t.Fatalf("got codec 0x%x, wanted 0x%x", got, cid.DagJSON)
+	}
+
+	parent, err := qp.BuildMap(basicnode.Prototype.Any, 3, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "name", qp.String("parent"))
+		qp.MapEntry(ma, "child", qp.Link(cidlink.Link{Cid: childCid}))
+		qp.MapEntry(ma, "file", qp.Link(cidlink.Link{Cid: file}))
+	})
+	if err != nil {
+		t.Fatalf("failed to build node: %v", err)
+	}
+	root, err := client.PutDag(ctx, parent, cid.DagCBOR, WithName("parent"))
+	if err != nil {
+		t.Fatalf("failed to put parent: %v", err)
+	}
+	if !srv.HasUpload(root) {
+		t.Fatalf("upload %s not found", root)
+	}
+
+	// Read the DAG back with a client holding none of its blocks.
+	dag, err := newFakeClient(t, srv).GetDag(ctx, root)
+	if err != nil {
+		t.Fatalf("failed to get DAG: %v", err)
+	}
+	if dag.Root != root {
+		t.Fatalf("got root %s, wanted %s", dag.Root, root)
+	}
+	n, err := dag.Lookup(ctx, "child/name")
+	if err != nil {
+		t.Fatalf("failed to look up child name: %v", err)
+	}
+	if s, _ := n.AsString(); s != "child" {
+		t.Fatalf("got name %q, wanted %q", s, "child")
+	}
+	var paths []string
+	err = dag.Walk(ctx, func(p traversal.Progress, n datamodel.Node) error {
+		paths = append(paths, p.Path.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk DAG: %v", err)
+	}
+	var found bool
+	for _, p := range paths {
+		if p == "child/name" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("walk did not follow link to child, visited %v", paths)
+	}
+
+	// Structured data is not UnixFS.
+	res, err := client.Get(ctx, root)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if _, _, err := res.Files(); !errors.Is(err, w3http.ErrNotUnixFS) {
+		t.Fatalf("got error %v, wanted %v", err, w3http.ErrNotUnixFS)
+	}
+}
+
+// hasErrDatastore is a datastore whose Has method fails after it has been
+// called n times.
+type hasErrDatastore struct {
+	ds.Batching
+	n int32
+}
+
+func (d *hasErrDatastore) Has(ctx context.Context, key ds.Key) (bool, error) {
+	if atomic.AddInt32(&d.n, -1) < 0 {
+		return false, errors.New("has failed")
+	}
+	return d.Batching.Has(ctx, key)
+}
+
+func TestPutDagHasError(t *testing.T) {
+	srv := w3stest.NewServer(w3stest.WithToken(validToken))
+	defer srv.Close()
+	// Has is called once to store the node, then for the block it links to.
+	store := &hasErrDatastore{Batching: dssync.MutexWrap(ds.NewMapDatastore()), n: 1}
+	client, err := NewClient(WithEndpoint(srv.URL), WithToken(validToken), WithDatastore(store))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	child, _ := cid.Parse(helloRoot)
+	node, err := qp.BuildMap(basicnode.Prototype.Any, 1, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "child", qp.Link(cidlink.Link{Cid: child}))
+	})
+	if err != nil {
+		t.Fatalf("failed to build node: %v", err)
+	}
+	_, err = client.PutDag(context.Background(), node, cid.DagCBOR)
+	if err == nil {
+		t.Fatalf("put succeeded although the blockstore failed")
+	}
+	if srv.Requests() != 0 {
+		t.Fatalf("got %d requests, wanted %d", srv.Requests(), 0)
+	}
+}
+
+func TestPutDagUnsupportedCodec(t *testing.T) {
+	srv := w3stest.NewServer(w3stest.WithToken(validToken))
+	defer srv.Close()
+	_, err := newFakeClient(t, srv).PutDag(context.Background(), basicnode.NewString("x"), cid.DagProtobuf)
+	if err == nil {
+		t.Fatalf("put with DAG-PB codec did not fail")
+	}
+}
@@ -0,0 +1,264 @@
+// Package dagdiff compares two UnixFS DAGs, reporting the paths that were
+// added, removed or modified between them.
+package dagdiff
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/web3-storage/go-w3s-client/fs/adapter"
+)
+
+// ChangeType is the kind of a change to a path.
+type ChangeType int
+
+const (
+	// Added is a path that only exists in the new DAG.
+	Added ChangeType = iota
+	// Removed is a path that only exists in the old DAG.
+	Removed
+	// Modified is a file whose content differs between the DAGs.
+	Modified
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	default:
+		return "unknown"
+	}
+}
+
+// Change is a difference between two DAGs.
+type Change struct {
+	Type ChangeType
+	// Path is the slash separated path of the file or directory, starting
+	// with "/".
+	Path  string
+	IsDir bool
+	// Before and After are the CIDs of the path in the old and new DAGs.
+	// Before is undefined for added paths and After for removed ones.
+	Before cid.Cid
+	After  cid.Cid
+	// BeforeSize and AfterSize are the sizes in bytes of the file in the old
+	// and new DAGs. They are zero for directories.
+	BeforeSize int64
+	AfterSize  int64
+}
+
+// Delta returns the number of bytes the change adds, which is negative if it
+// removes bytes.
+func (c Change) Delta() int64 {
+	return c.AfterSize - c.BeforeSize
+}
+
+// Diff compares the UnixFS DAGs with the roots a and b, whose blocks must be
+// available from the block service. Subtrees with identical CIDs are skipped
+// without being read. Changes are returned in lexical path order, with a
+// directory before its contents. The contents of added and removed
+// directories are reported as well as the directories themselves.
+func Diff(ctx context.Context, bsvc blockservice.BlockService, a, b cid.Cid) ([]Change, error) {
+	fsa, err := adapter.NewFsWithContext(ctx, a, bsvc)
+	if err != nil {
+		return nil, err
+	}
+	fsb, err := adapter.NewFsWithContext(ctx, b, bsvc)
+	if err != nil {
+		return nil, err
+	}
+	return DiffFS(fsa, fsb)
+}
+
+// DiffFS compares two file systems created by the fs/adapter package, such as
+// those returned by the Files method of a response to the client Get method.
+// Paths are compared by the CID returned by the Sys method of their
+// fs.FileInfo.
+func DiffFS(a, b fs.FS) ([]Change, error) {
+	d := differ{a: a, b: b}
+	err := d.diff("/")
+	if err != nil {
+		return nil, err
+	}
+	return d.changes, nil
+}
+
+type differ struct {
+	a, b    fs.FS
+	changes []Change
+}
+
+// entry is a path opened in one of the file systems.
+type entry struct {
+	info fs.FileInfo
+	cid  cid.Cid
+}
+
+func stat(fsys fs.FS, name string) (entry, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return entry{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return entry{}, err
+	}
+	c, _ := info.Sys().(cid.Cid)
+	return entry{info, c}, nil
+}
+
+func (e entry) size() int64 {
+	if e.info.IsDir() {
+		return 0
+	}
+	return e.info.Size()
+}
+
+func (d *differ) diff(name string) error {
+	ea, err := stat(d.a, name)
+	if err != nil {
+		return err
+	}
+	eb, err := stat(d.b, name)
+	if err != nil {
+		return err
+	}
+	if !ea.cid.Defined() || !eb.cid.Defined() {
+		return fmt.Errorf("no CID for %s", name)
+	}
+	if ea.cid == eb.cid {
+		return nil
+	}
+
+	switch {
+	case ea.info.IsDir() && eb.info.IsDir():
+		return d.diffDir(name)
+	case ea.info.IsDir() || eb.info.IsDir():
+		// A file replaced by a directory or the reverse.
+		if err := d.report(d.a, name, ea, Removed); err != nil {
+			return err
+		}
+		return d.report(d.b, name, eb, Added)
+	}
+
+	d.changes = append(d.changes, Change{
+		Type:       Modified,
+		Path:       name,
+		Before:     ea.cid,
+		After:      eb.cid,
+		BeforeSize: ea.size(),
+		AfterSize:  eb.size(),
+	})
+	return nil
+}
+
+func (d *differ) diffDir(name string) error {
+	na, err := readDirNames(d.a, name)
+	if err != nil {
+		return err
+	}
+	nb, err := readDirNames(d.b, name)
+	if err != nil {
+		return err
+	}
+
+	names := map[string]struct{}{}
+	for n := range na {
+		names[n] = struct{}{}
+	}
+	for n := range nb {
+		names[n] = struct{}{}
+	}
+	for _, n := range sortedNames(names) {
+		p := path.Join(name, n)
+		_, inA := na[n]
+		_, inB := nb[n]
+		switch {
+		case inA && inB:
+			err = d.diff(p)
+		case inA:
+			err = d.reportPath(d.a, p, Removed)
+		default:
+			err = d.reportPath(d.b, p, Added)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *differ) reportPath(fsys fs.FS, name string, t ChangeType) error {
+	e, err := stat(fsys, name)
+	if err != nil {
+		return err
+	}
+	return d.report(fsys, name, e, t)
+}
+
+// report records a path that only exists in one of the file systems, along
+// with the contents of directories.
+func (d *differ) report(fsys fs.FS, name string, e entry, t ChangeType) error {
+	c := Change{Type: t, Path: name, IsDir: e.info.IsDir()}
+	if t == Added {
+		c.After, c.AfterSize = e.cid, e.size()
+	} else {
+		c.Before, c.BeforeSize = e.cid, e.size()
+	}
+	d.changes = append(d.changes, c)
+	if !e.info.IsDir() {
+		return nil
+	}
+
+	names, err := readDirNames(fsys, name)
+	if err != nil {
+		return err
+	}
+	for _, n := range sortedNames(names) {
+		err := d.reportPath(fsys, path.Join(name, n), t)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readDirNames(fsys fs.FS, name string) (map[string]struct{}, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dir, ok := f.(fs.ReadDirFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	ents, err := dir.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]struct{}, len(ents))
+	for _, ent := range ents {
+		names[ent.Name()] = struct{}{}
+	}
+	return names, nil
+}
+
+func sortedNames(names map[string]struct{}) []string {
+	sorted := make([]string, 0, len(names))
+	for n := range names {
+		sorted = append(sorted, n)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,109 @@
+package dagdiff
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	bserv "github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/ipfs/go-merkledag"
+	"github.com/web3-storage/go-w3s-client/adder"
+)
+
+// addDir imports the "backup" directory of fsys, returning the root CID of a
+// directory wrapping it.
+func addDir(t *testing.T, bsvc bserv.BlockService, fsys fstest.MapFS) cid.Cid {
+	ctx := context.Background()
+	a, err := adder.NewAdder(ctx, merkledag.NewDAGService(bsvc))
+	if err != nil {
+		t.Fatalf("failed to create adder: %v", err)
+	}
+	f, err := fsys.Open("backup")
+	if err != nil {
+		t.Fatalf("failed to open directory: %v", err)
+	}
+	root, err := a.Add(f, "", fsys)
+	if err != nil {
+		t.Fatalf("failed to add directory: %v", err)
+	}
+	return root
+}
+
+func TestDiff(t *testing.T) {
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	bsvc := bserv.New(bs, nil)
+
+	before := addDir(t, bsvc, fstest.MapFS{
+		"backup/same.txt":        {Data: []byte("same")},
+		"backup/changed.txt":     {Data: []byte("before")},
+		"backup/removed.txt":     {Data: []byte("removed")},
+		"backup/old/file.txt":    {Data: []byte("old")},
+		"backup/shared/file.txt": {Data: []byte("shared")},
+		"backup/became-dir":      {Data: []byte("file")},
+	})
+	after := addDir(t, bsvc, fstest.MapFS{
+		"backup/same.txt":            {Data: []byte("same")},
+		"backup/changed.txt":         {Data: []byte("after, longer")},
+		"backup/added.txt":           {Data: []byte("added")},
+		"backup/new/file.txt":        {Data: []byte("new")},
+		"backup/shared/file.txt":     {Data: []byte("shared")},
+		"backup/became-dir/file.txt": {Data: []byte("nested")},
+	})
+
+	// Identical subtrees are not descended into, so their blocks are not
+	// needed.
+	shared, err := merkledag.V1CidPrefix().Sum([]byte("shared"))
+	if err != nil {
+		t.Fatalf("failed to compute CID: %v", err)
+	}
+	shared = cid.NewCidV1(cid.Raw, shared.Hash())
+	if ok, _ := bs.Has(context.Background(), shared); !ok {
+		t.Fatalf("block %s of shared file not found", shared)
+	}
+	if err := bs.DeleteBlock(context.Background(), shared); err != nil {
+		t.Fatalf("failed to delete block: %v", err)
+	}
+
+	changes, err := Diff(context.Background(), bsvc, before, after)
+	if err != nil {
+		t.Fatalf("failed to diff: %v", err)
+	}
+
+	wanted := []struct {
+		typ   ChangeType
+		path  string
+		delta int64
+	}{
+		{Added, "/backup/added.txt", 5},
+		{Removed, "/backup/became-dir", -4},
+		{Added, "/backup/became-dir", 0},
+		{Added, "/backup/became-dir/file.txt", 6},
+		{Modified, "/backup/changed.txt", 7},
+		{Added, "/backup/new", 0},
+		{Added, "/backup/new/file.txt", 3},
+		{Removed, "/backup/old", 0},
+		{Removed, "/backup/old/file.txt", -3},
+		{Removed, "/backup/removed.txt", -7},
+	}
+	if len(changes) != len(wanted) {
+		t.Fatalf("got %d changes, wanted %d: %v", len(changes), len(wanted), changes)
+	}
+	for i, w := range wanted {
+		c := changes[i]
+		if c.Type != w.typ || c.Path != w.path || c.Delta() != w.delta {
+			t.Fatalf("got change %d %s %s (%d bytes), wanted %s %s (%d bytes)", i, c.Type, c.Path, c.Delta(), w.typ, w.path, w.delta)
+		}
+	}
+
+	changes, err = Diff(context.Background(), bsvc, before, before)
+	if err != nil {
+		t.Fatalf("failed to diff: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("got %d changes for identical roots, wanted 0", len(changes))
+	}
+}
@@ -0,0 +1,34 @@
+package w3s
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+)
+
+// DeleteResult is the result of deleting an upload.
+type DeleteResult struct {
+	Cid cid.Cid
+}
+
+// Delete removes an upload from the list of uploads for the user. Note that
+// this does not remove the data from the IPFS network or Filecoin deals that
+// have already been made.
+func (c *client) Delete(ctx context.Context, cid cid.Cid) (*DeleteResult, error) {
+	req, err := c.newRequest(ctx, "DELETE", fmt.Sprintf("/user/uploads/%s", cid), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.cfg.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return nil, newResponseError(res)
+	}
+
+	return &DeleteResult{Cid: cid}, nil
+}
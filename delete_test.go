@@ -0,0 +1,86 @@
+package w3s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+)
+
+var deleteUploadHandler = func(w http.ResponseWriter, r *http.Request) {
+	if !hasValidToken(w, r) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.URL.Path != "/user/uploads/"+helloRoot {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{
+			"name":    "HTTPError",
+			"message": "Upload not found",
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"cid": helloRoot})
+}
+
+func TestDeleteHappyPath(t *testing.T) {
+	routes := routeMap{
+		"/user/uploads/": {
+			http.MethodDelete: deleteUploadHandler,
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	c, _ := cid.Parse(helloRoot)
+
+	res, err := client.Delete(context.Background(), c)
+	if err != nil {
+		t.Fatalf("failed to delete upload: %v", err)
+	}
+
+	if res.Cid.String() != helloRoot {
+		t.Fatalf("got cid %s, wanted %s", res.Cid.String(), helloRoot)
+	}
+}
+
+func TestDeleteNotFound(t *testing.T) {
+	routes := routeMap{
+		"/user/uploads/": {
+			http.MethodDelete: deleteUploadHandler,
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	c, _ := cid.Parse(thanksRoot)
+
+	_, err = client.Delete(context.Background(), c)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got error %v, wanted %v", err, ErrNotFound)
+	}
+
+	var rerr *ResponseError
+	if !errors.As(err, &rerr) || rerr.Message != "Upload not found" {
+		t.Fatalf("expected response error with message, got %v", err)
+	}
+}
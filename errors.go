@@ -0,0 +1,54 @@
+package w3s
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrNotFound is matched (using errors.Is) by a ResponseError for a 404
+// response.
+var ErrNotFound = errors.New("not found")
+
+// ResponseError is an error returned when the API responds with an unexpected
+// HTTP status code.
+type ResponseError struct {
+	StatusCode int
+	// Code is the error code or name reported by the API, if any.
+	Code string
+	// Message is the error message reported by the API, if any.
+	Message string
+}
+
+func (e *ResponseError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("unexpected response status: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected response status: %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *ResponseError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// newResponseError creates a ResponseError from a response, decoding the error
+// details from the body if present. It does not close the body.
+func newResponseError(res *http.Response) *ResponseError {
+	e := ResponseError{StatusCode: res.StatusCode}
+	var raw struct {
+		Name    string `json:"name"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	err := json.NewDecoder(io.LimitReader(res.Body, 1024*64)).Decode(&raw)
+	if err == nil {
+		e.Code = raw.Code
+		if e.Code == "" {
+			e.Code = raw.Name
+		}
+		e.Message = raw.Message
+	}
+	return &e
+}
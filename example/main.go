@@ -13,15 +13,13 @@ import (
 )
 
 // Usage:
-// TOKEN="API_TOKEN" go run ./main.go
+// W3S_TOKEN="API_TOKEN" go run ./main.go
 func main() {
-	c, err := w3s.NewClient(
-		w3s.WithEndpoint(os.Getenv("ENDPOINT")),
-		w3s.WithToken(os.Getenv("TOKEN")),
-	)
+	c, err := w3s.NewClientFromEnv()
 	if err != nil {
 		panic(err)
 	}
+	defer c.Close()
 
 	// cid := putSingleFile(c)
 	// getStatusForCid(c, cid)
@@ -75,12 +73,12 @@ func putDirectory(c w3s.Client) cid.Cid {
 }
 
 func putFile(c w3s.Client, f fs.File, opts ...w3s.PutOption) cid.Cid {
-	cid, err := c.Put(context.Background(), f, opts...)
+	roots, err := c.Put(context.Background(), f, opts...)
 	if err != nil {
 		panic(err)
 	}
-	fmt.Printf("https://%v.ipfs.dweb.link\n", cid)
-	return cid
+	fmt.Printf("https://%v.ipfs.dweb.link\n", roots[0])
+	return roots[0]
 }
 
 func getStatusForCid(c w3s.Client, cid cid.Cid) {
@@ -135,7 +133,7 @@ func listUploads(c w3s.Client) {
 	}
 
 	for {
-		u, err := uploads.Next()
+		u, err := uploads.Next(context.Background())
 		if err != nil {
 			// finished successfully
 			if err == io.EOF {
@@ -0,0 +1,278 @@
+package w3s
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bserv "github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	"github.com/ipfs/go-unixfs"
+	"github.com/ipld/go-car"
+	w3http "github.com/web3-storage/go-w3s-client/http"
+)
+
+// withMetadata appends UnixFS 1.5 mode and mtime fields to encoded UnixFS
+// data.
+func withMetadata(data []byte, mode uint32, mtime time.Time) []byte {
+	data = append(data, 7<<3)
+	data = appendUvarint(data, uint64(mode))
+
+	var m []byte
+	m = append(m, 1<<3)
+	m = appendUvarint(m, uint64(mtime.Unix()))
+	m = append(m, 2<<3|5)
+	var nanos [4]byte
+	binary.LittleEndian.PutUint32(nanos[:], uint32(mtime.Nanosecond()))
+	m = append(m, nanos[:]...)
+
+	data = append(data, 8<<3|2)
+	data = appendUvarint(data, uint64(len(m)))
+	return append(data, m...)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+type testEntry struct {
+	name  string
+	data  []byte
+	mode  uint32
+	mtime time.Time
+}
+
+// buildDirCar builds a CAR of a directory containing the passed files and a
+// nested directory, returning the root CID and CAR bytes.
+func buildDirCar(t *testing.T, files []testEntry, sub testEntry) (cid.Cid, []byte) {
+	ctx := context.Background()
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	dag := merkledag.NewDAGService(bserv.New(bs, nil))
+
+	addDir := func(entries []testEntry, mode uint32, mtime time.Time, extra ...*ipld.Link) *merkledag.ProtoNode {
+		dir := merkledag.NodeWithData(withMetadata(unixfs.FolderPBData(), mode, mtime))
+		for _, e := range entries {
+			data := unixfs.FilePBData(e.data, uint64(len(e.data)))
+			if e.mode != 0 {
+				data = withMetadata(data, e.mode, e.mtime)
+			}
+			nd := merkledag.NodeWithData(data)
+			if err := dag.Add(ctx, nd); err != nil {
+				t.Fatalf("failed to add file: %v", err)
+			}
+			if err := dir.AddNodeLink(e.name, nd); err != nil {
+				t.Fatalf("failed to link file: %v", err)
+			}
+		}
+		for _, l := range extra {
+			dir.AddRawLink(l.Name, l)
+		}
+		if err := dag.Add(ctx, dir); err != nil {
+			t.Fatalf("failed to add directory: %v", err)
+		}
+		return dir
+	}
+
+	nested := addDir([]testEntry{{name: "nested.txt", data: sub.data}}, sub.mode, sub.mtime)
+	root := addDir(files, 0755, sub.mtime, &ipld.Link{Name: sub.name, Cid: nested.Cid()})
+
+	var buf bytes.Buffer
+	if err := car.WriteCar(ctx, dag, []cid.Cid{root.Cid()}, &buf); err != nil {
+		t.Fatalf("failed to write car: %v", err)
+	}
+	return root.Cid(), buf.Bytes()
+}
+
+func extractCar(t *testing.T, root cid.Cid, carBytes []byte, dir string, opts ...w3http.ExtractOption) error {
+	routes := routeMap{
+		"/car/" + root.String(): {
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/car")
+				w.Write(carBytes)
+			},
+		},
+	}
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	res, err := client.Get(context.Background(), root)
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	defer res.Body.Close()
+	return res.ExtractTo(dir, opts...)
+}
+
+func TestExtractTo(t *testing.T) {
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	var files []testEntry
+	for i := 0; i < 20; i++ {
+		files = append(files, testEntry{name: string(rune('a'+i)) + ".txt", data: []byte{byte(i)}})
+	}
+	files = append(files, testEntry{name: "script.sh", data: []byte("#!/bin/sh\n"), mode: 0700, mtime: mtime})
+	root, carBytes := buildDirCar(t, files, testEntry{name: "sub", data: []byte("nested"), mode: 0750, mtime: mtime})
+
+	dir := t.TempDir()
+	err := extractCar(t, root, carBytes, dir, w3http.WithExtractConcurrency(4))
+	if err != nil {
+		t.Fatalf("failed to extract: %v", err)
+	}
+
+	for _, f := range files {
+		b, err := os.ReadFile(filepath.Join(dir, f.name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", f.name, err)
+		}
+		if !bytes.Equal(b, f.data) {
+			t.Fatalf("got %q in %s, wanted %q", b, f.name, f.data)
+		}
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "sub", "nested.txt"))
+	if err != nil || string(b) != "nested" {
+		t.Fatalf("got %q, %v reading nested file, wanted %q", b, err, "nested")
+	}
+
+	for _, p := range []struct {
+		path string
+		mode os.FileMode
+	}{{"script.sh", 0700}, {"sub", 0750}} {
+		fi, err := os.Stat(filepath.Join(dir, p.path))
+		if err != nil {
+			t.Fatalf("failed to stat %s: %v", p.path, err)
+		}
+		if fi.Mode().Perm() != p.mode {
+			t.Fatalf("got mode %v for %s, wanted %v", fi.Mode().Perm(), p.path, p.mode)
+		}
+		if !fi.ModTime().Equal(mtime) {
+			t.Fatalf("got mtime %v for %s, wanted %v", fi.ModTime(), p.path, mtime)
+		}
+	}
+}
+
+func TestExtractToConflicts(t *testing.T) {
+	files := []testEntry{{name: "a.txt", data: []byte("new")}}
+	root, carBytes := buildDirCar(t, files, testEntry{name: "sub", data: []byte("nested")})
+
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(existing, []byte("old"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	err := extractCar(t, root, carBytes, dir)
+	if !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("got error %v, wanted %v", err, fs.ErrExist)
+	}
+
+	err = extractCar(t, root, carBytes, dir, w3http.WithConflictPolicy(w3http.ConflictSkip))
+	if err != nil {
+		t.Fatalf("failed to extract: %v", err)
+	}
+	if b, _ := os.ReadFile(existing); string(b) != "old" {
+		t.Fatalf("got %q, wanted existing file to be kept", b)
+	}
+
+	err = extractCar(t, root, carBytes, dir, w3http.WithConflictPolicy(w3http.ConflictOverwrite))
+	if err != nil {
+		t.Fatalf("failed to extract: %v", err)
+	}
+	if b, _ := os.ReadFile(existing); string(b) != "new" {
+		t.Fatalf("got %q, wanted existing file to be overwritten", b)
+	}
+}
+
+func TestExtractToUnsafeNames(t *testing.T) {
+	for _, name := range []string{"..", "../evil.txt", "sub/../../evil.txt"} {
+		root, carBytes := buildDirCar(t, []testEntry{{name: name, data: []byte("evil")}}, testEntry{name: "sub", data: []byte("nested")})
+
+		parent := t.TempDir()
+		dir := filepath.Join(parent, "out")
+		err := extractCar(t, root, carBytes, dir)
+		if !errors.Is(err, w3http.ErrUnsafePath) {
+			t.Fatalf("got error %v for %q, wanted %v", err, name, w3http.ErrUnsafePath)
+		}
+		if _, err := os.Stat(filepath.Join(parent, "evil.txt")); err == nil {
+			t.Fatalf("file %q was written outside of the destination", name)
+		}
+	}
+}
+
+// buildSymlinkCar builds a CAR of a directory containing a/b/s, a symlink to
+// "../..", and t, a symlink to target.
+func buildSymlinkCar(t *testing.T, target string) (cid.Cid, []byte) {
+	ctx := context.Background()
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	dag := merkledag.NewDAGService(bserv.New(bs, nil))
+
+	add := func(data []byte, links map[string]ipld.Node) ipld.Node {
+		nd := merkledag.NodeWithData(data)
+		for name, l := range links {
+			if err := nd.AddNodeLink(name, l); err != nil {
+				t.Fatalf("failed to link %s: %v", name, err)
+			}
+		}
+		if err := dag.Add(ctx, nd); err != nil {
+			t.Fatalf("failed to add node: %v", err)
+		}
+		return nd
+	}
+	symlink := func(target string) ipld.Node {
+		data, err := unixfs.SymlinkData(target)
+		if err != nil {
+			t.Fatalf("failed to encode symlink: %v", err)
+		}
+		return add(data, nil)
+	}
+
+	b := add(unixfs.FolderPBData(), map[string]ipld.Node{"s": symlink("../..")})
+	a := add(unixfs.FolderPBData(), map[string]ipld.Node{"b": b})
+	root := add(unixfs.FolderPBData(), map[string]ipld.Node{"a": a, "t": symlink(target)})
+
+	var buf bytes.Buffer
+	if err := car.WriteCar(ctx, dag, []cid.Cid{root.Cid()}, &buf); err != nil {
+		t.Fatalf("failed to write car: %v", err)
+	}
+	return root.Cid(), buf.Bytes()
+}
+
+func TestExtractToChainedSymlinks(t *testing.T) {
+	// a/b/s resolves to the destination, so following it and climbing two
+	// levels escapes it, although the target is inside it when read lexically.
+	root, carBytes := buildSymlinkCar(t, "a/b/s/../..")
+	dir := filepath.Join(t.TempDir(), "out")
+	err := extractCar(t, root, carBytes, dir)
+	if !errors.Is(err, w3http.ErrUnsafePath) {
+		t.Fatalf("got error %v, wanted %v", err, w3http.ErrUnsafePath)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "t")); err == nil {
+		t.Fatalf("symlink escaping the destination was written")
+	}
+
+	root, carBytes = buildSymlinkCar(t, "a/b/s/a")
+	dir = filepath.Join(t.TempDir(), "out")
+	if err := extractCar(t, root, carBytes, dir); err != nil {
+		t.Fatalf("failed to extract symlink inside the destination: %v", err)
+	}
+	fi, err := os.Stat(filepath.Join(dir, "t", "b"))
+	if err != nil || !fi.IsDir() {
+		t.Fatalf("got %v, wanted t/b to resolve to a directory", err)
+	}
+}
@@ -66,7 +66,7 @@ func (fs *unixfsFs) Open(name string) (fs.File, error) {
 		ipfsPath = path.FromString(fmt.Sprintf("/ipfs/%s%s", fs.rootCid, name))
 	}
 
-	cid, rest, err := fs.resolver.ResolveToLastNode(fs.ctx, ipfsPath)
+	c, rest, err := fs.resolver.ResolveToLastNode(fs.ctx, ipfsPath)
 	if err != nil {
 		return nil, err
 	}
@@ -74,7 +74,7 @@ func (fs *unixfsFs) Open(name string) (fs.File, error) {
 		return nil, errors.New("cannot resolve to last node")
 	}
 
-	nd, err := fs.dsvc.Get(fs.ctx, cid)
+	nd, err := fs.dsvc.Get(fs.ctx, c)
 	if err != nil {
 		return nil, err
 	}
@@ -89,7 +89,7 @@ func (fs *unixfsFs) Open(name string) (fs.File, error) {
 		return nil, err
 	}
 
-	return NewFile(fname, f)
+	return newFile(fname, f, c)
 }
 
 var _ fs.FS = (*unixfsFs)(nil)
@@ -101,6 +101,10 @@ type unixfsFile struct {
 
 // NewFile creates an fs.File that is backed by an IPFS files.Node.
 func NewFile(name string, node files.Node) (fs.File, error) {
+	return newFile(name, node, cid.Undef)
+}
+
+func newFile(name string, node files.Node, c cid.Cid) (fs.File, error) {
 	size, err := node.Size()
 	if err != nil {
 		return nil, err
@@ -112,6 +116,7 @@ func NewFile(name string, node files.Node) (fs.File, error) {
 			size:    size,
 			modTime: time.Now(),
 			isDir:   isDir,
+			cid:     c,
 		},
 		node: node,
 	}, nil
@@ -166,6 +171,7 @@ type unixfsFileInfo struct {
 	size    int64
 	modTime time.Time
 	isDir   bool
+	cid     cid.Cid
 }
 
 func (i *unixfsFileInfo) Name() string {
@@ -191,8 +197,13 @@ func (i *unixfsFileInfo) IsDir() bool {
 	return i.Mode().IsDir()
 }
 
+// Sys returns the cid.Cid of the file for files returned by the Open method of
+// the FS, or nil.
 func (i *unixfsFileInfo) Sys() interface{} {
-	return nil
+	if !i.cid.Defined() {
+		return nil
+	}
+	return i.cid
 }
 
 var _ fs.FileInfo = (*unixfsFileInfo)(nil)
@@ -0,0 +1,228 @@
+package w3s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	bserv "github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	"github.com/ipld/go-car"
+	w3http "github.com/web3-storage/go-w3s-client/http"
+)
+
+// GatewayStrategy determines how Get retrieves content from gateways.
+type GatewayStrategy int
+
+const (
+	// GatewaySequential tries each gateway in turn, in the order passed to
+	// WithGateways, until one returns a verified response.
+	GatewaySequential GatewayStrategy = iota
+	// GatewayRace requests the content from all gateways at once and uses the
+	// first verified response, cancelling the others.
+	GatewayRace
+)
+
+// ErrUnverified is returned when a gateway response does not contain the
+// complete, valid DAG that was requested.
+var ErrUnverified = errors.New("unverified gateway response")
+
+// defaultGatewayMaxSize is the default limit on the size of a CAR read from a
+// gateway, which is held in memory while it is verified.
+const defaultGatewayMaxSize = 1 << 30
+
+// getFromGateways retrieves the DAG with the passed root from the configured
+// gateways.
+func (c *client) getFromGateways(ctx context.Context, root cid.Cid) (*w3http.Web3Response, error) {
+	var (
+		dag ipld.DAGService
+		req *http.Request
+		err error
+	)
+	if c.cfg.gatewayStrategy == GatewayRace {
+		dag, req, err = c.raceGateways(ctx, root)
+	} else {
+		var errs []string
+		for _, gw := range c.cfg.gateways {
+			dag, req, err = c.fetchFromGateway(ctx, gw, root)
+			if err == nil {
+				break
+			}
+			c.cfg.log.Warnf("getting %s from gateway %s: %v", root, gw, err)
+			errs = append(errs, err.Error())
+		}
+		if err != nil {
+			err = fmt.Errorf("all gateways failed: %s", strings.Join(errs, "; "))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.cfg.log.Infof("got %s from gateway %s", root, req.URL.Host)
+	res := carResponse(ctx, req.WithContext(ctx), dag, root)
+	return w3http.NewWeb3Response(res, c.blockService(), w3http.WithLogger(c.cfg.log)), nil
+}
+
+func (c *client) raceGateways(ctx context.Context, root cid.Cid) (ipld.DAGService, *http.Request, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		gw  string
+		dag ipld.DAGService
+		req *http.Request
+		err error
+	}
+	results := make(chan result, len(c.cfg.gateways))
+	for _, gw := range c.cfg.gateways {
+		go func(gw string) {
+			dag, req, err := c.fetchFromGateway(ctx, gw, root)
+			results <- result{gw, dag, req, err}
+		}(gw)
+	}
+
+	var errs []string
+	for range c.cfg.gateways {
+		r := <-results
+		if r.err == nil {
+			return r.dag, r.req, nil
+		}
+		c.cfg.log.Warnf("getting %s from gateway %s: %v", root, r.gw, r.err)
+		errs = append(errs, r.err.Error())
+	}
+	return nil, nil, fmt.Errorf("all gateways failed: %s", strings.Join(errs, "; "))
+}
+
+// fetchFromGateway downloads the DAG with the passed root as a CAR from a
+// trustless gateway into a new in-memory store, verifying that each block
+// matches its CID and that the CAR holds the complete DAG and nothing else.
+func (c *client) fetchFromGateway(ctx context.Context, gateway string, root cid.Cid) (ipld.DAGService, *http.Request, error) {
+	ctx, span := c.startSpan(ctx, "fetchFromGateway")
+	var err error
+	defer func() { endSpan(span, err) }()
+
+	u := fmt.Sprintf("%s/ipfs/%s?format=car", strings.TrimSuffix(gateway, "/"), root)
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The auth token and middlewares are for the API only, so the request is
+	// sent with a client that does not add headers to it.
+	req.Header.Add("Accept", "application/vnd.ipld.car")
+	req.Header.Add("X-Client", clientName)
+	res, err := c.cfg.gatewayHC.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		err = fmt.Errorf("unexpected response status: %d", res.StatusCode)
+		return nil, nil, err
+	}
+
+	max := c.cfg.gatewayMaxSize
+	if max <= 0 {
+		max = defaultGatewayMaxSize
+	}
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	dag := merkledag.NewDAGService(bserv.New(bs, nil))
+	loaded, err := loadVerifiedCar(ctx, bs, &sizeLimitReader{r: res.Body, n: max}, root)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Blocks are only kept if they are reached by walking the DAG from the
+	// root, so a gateway cannot add blocks that were not requested.
+	reached := cid.NewSet()
+	var missing []cid.Cid
+	err = merkledag.Walk(ctx, func(ctx context.Context, c cid.Cid) ([]*ipld.Link, error) {
+		nd, err := dag.Get(ctx, c)
+		if ipld.IsNotFound(err) {
+			missing = append(missing, c)
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nd.Links(), nil
+	}, root, reached.Visit)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(missing) > 0 {
+		err = fmt.Errorf("%w: %d blocks missing from DAG, including %s", ErrUnverified, len(missing), missing[0])
+		return nil, nil, err
+	}
+	if extra := loaded.Len() - reached.Len(); extra > 0 {
+		err = fmt.Errorf("%w: %d blocks are not part of the DAG", ErrUnverified, extra)
+		return nil, nil, err
+	}
+	return dag, req, nil
+}
+
+// sizeLimitReader reads from r, failing once more than n bytes are read.
+type sizeLimitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		// Only fail if there is more data to read.
+		var b [1]byte
+		n, err := l.r.Read(b[:])
+		if n > 0 {
+			return 0, fmt.Errorf("%w: response is larger than the limit", ErrUnverified)
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// loadVerifiedCar reads a CAR with the passed root into the blockstore,
+// failing if any block does not match its CID. It returns the CIDs of the
+// blocks read.
+func loadVerifiedCar(ctx context.Context, bs blockstore.Blockstore, r io.Reader, root cid.Cid) (*cid.Set, error) {
+	cr, err := car.NewCarReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(cr.Header.Roots) == 0 || cr.Header.Roots[0] != root {
+		return nil, fmt.Errorf("%w: CAR roots %v do not match %s", ErrUnverified, cr.Header.Roots, root)
+	}
+	loaded := cid.NewSet()
+	for {
+		b, err := cr.Next()
+		if err == io.EOF {
+			return loaded, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		sum, err := b.Cid().Prefix().Sum(b.RawData())
+		if err != nil {
+			return nil, err
+		}
+		if !sum.Equals(b.Cid()) {
+			return nil, fmt.Errorf("%w: block data does not match CID %s", ErrUnverified, b.Cid())
+		}
+		err = bs.Put(ctx, b)
+		if err != nil {
+			return nil, err
+		}
+		loaded.Add(b.Cid())
+	}
+}
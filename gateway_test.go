@@ -0,0 +1,219 @@
+package w3s
+
+import (
+	"context"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+)
+
+func startGateway(t *testing.T, carHex string, requests *int32) *httptest.Server {
+	carbytes, err := hex.DecodeString(carHex)
+	if err != nil {
+		t.Fatalf("failed to decode car: %v", err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("auth token was sent to gateway")
+		}
+		if r.Header.Get("X-Request-Id") != "" || r.Header.Get("X-Custom") != "" {
+			t.Errorf("middleware headers were sent to gateway")
+		}
+		if r.URL.Path != "/ipfs/"+helloRoot || r.URL.Query().Get("format") != "car" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.ipld.car")
+		w.Write(carbytes)
+	}))
+}
+
+func TestGetFromGateways(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer api.Close()
+
+	// The last byte of the car is part of the file data, so changing it
+	// corrupts the leaf block.
+	corrupt := helloCarHex[:len(helloCarHex)-2] + "00"
+	var badRequests, wrongRequests, goodRequests int32
+	bad := startGateway(t, corrupt, &badRequests)
+	defer bad.Close()
+	wrong := startGateway(t, thanksCarHex, &wrongRequests)
+	defer wrong.Close()
+	good := startGateway(t, helloCarHex, &goodRequests)
+	defer good.Close()
+
+	root, _ := cid.Parse(helloRoot)
+	for _, strategy := range []GatewayStrategy{GatewaySequential, GatewayRace} {
+		atomic.StoreInt32(&goodRequests, 0)
+		client, err := NewClient(
+			WithEndpoint(api.URL),
+			WithToken(validToken),
+			WithGateways(bad.URL, wrong.URL, good.URL),
+			WithGatewayStrategy(strategy),
+		)
+		if err != nil {
+			t.Fatalf("failed to create client: %v", err)
+		}
+		res, err := client.Get(context.Background(), root)
+		if err != nil {
+			t.Fatalf("failed to get with strategy %d: %v", strategy, err)
+		}
+		if res.StatusCode != 200 {
+			t.Fatalf("got status %d, wanted %d", res.StatusCode, 200)
+		}
+		_, fsys, err := res.Files()
+		if err != nil {
+			t.Fatalf("failed to read files: %v", err)
+		}
+		b, err := fs.ReadFile(fsys, "/helloworld.txt")
+		if err != nil {
+			t.Fatalf("failed to read file: %v", err)
+		}
+		if string(b) != "Hello, world!" {
+			t.Fatalf("got %q, wanted %q", b, "Hello, world!")
+		}
+		if got := atomic.LoadInt32(&goodRequests); got != 1 {
+			t.Fatalf("got %d requests to gateway, wanted %d", got, 1)
+		}
+		// Raced requests to the failing gateways may be cancelled before they
+		// are sent, so they are only counted for sequential retrieval.
+		if strategy == GatewaySequential {
+			if b, w := atomic.LoadInt32(&badRequests), atomic.LoadInt32(&wrongRequests); b != 1 || w != 1 {
+				t.Fatalf("got %d and %d requests to failing gateways, wanted 1", b, w)
+			}
+		}
+	}
+}
+
+func TestGetFromGatewaysUnverified(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer api.Close()
+	var requests int32
+	wrong := startGateway(t, thanksCarHex, &requests)
+	defer wrong.Close()
+
+	client, err := NewClient(WithEndpoint(api.URL), WithToken(validToken), WithGateways(wrong.URL))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	root, _ := cid.Parse(helloRoot)
+	res, err := client.Get(context.Background(), root)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	// The API response is returned when no gateway can serve the content.
+	if res.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("got status %d, wanted %d", res.StatusCode, http.StatusInternalServerError)
+	}
+	if requests != 1 {
+		t.Fatalf("got %d requests to gateway, wanted %d", requests, 1)
+	}
+}
+
+func TestGetFromGatewaysRejected(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer api.Close()
+
+	// The blocks of another DAG, after the 0x3a byte header of its CAR.
+	extraBlocks := thanksCarHex[2+0x3a*2:]
+	tests := []struct {
+		name   string
+		carHex string
+		opts   []Option
+	}{
+		{"extra blocks", helloCarHex + extraBlocks, nil},
+		{"too large", helloCarHex, []Option{WithGatewayMaxSize(64)}},
+	}
+	for _, tt := range tests {
+		var requests int32
+		gw := startGateway(t, tt.carHex, &requests)
+		opts := append([]Option{WithEndpoint(api.URL), WithToken(validToken), WithGateways(gw.URL)}, tt.opts...)
+		client, err := NewClient(opts...)
+		if err != nil {
+			t.Fatalf("failed to create client: %v", err)
+		}
+		root, _ := cid.Parse(helloRoot)
+		res, err := client.Get(context.Background(), root)
+		gw.Close()
+		if err != nil {
+			t.Fatalf("failed to get: %v", err)
+		}
+		if res.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("%s: got status %d, wanted %d", tt.name, res.StatusCode, http.StatusInternalServerError)
+		}
+		if requests != 1 {
+			t.Fatalf("%s: got %d requests to gateway, wanted %d", tt.name, requests, 1)
+		}
+	}
+}
+
+func TestGatewaysSkipMiddleware(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer refreshed" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer api.Close()
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer unauthorized.Close()
+	var requests int32
+	good := startGateway(t, helloCarHex, &requests)
+	defer good.Close()
+
+	var refreshes int32
+	client, err := NewClient(
+		WithEndpoint(api.URL),
+		WithToken("expired"),
+		WithGateways(unauthorized.URL, good.URL),
+		WithMiddleware(
+			AddRequestID(),
+			AddHeaders(http.Header{"X-Custom": {"secret"}}),
+			RefreshAuth(func(context.Context) (string, error) {
+				atomic.AddInt32(&refreshes, 1)
+				return "refreshed", nil
+			}),
+		),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	root, _ := cid.Parse(helloRoot)
+	res, err := client.Get(context.Background(), root)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, wanted %d", res.StatusCode, http.StatusOK)
+	}
+	if requests != 1 {
+		t.Fatalf("got %d requests to gateway, wanted %d", requests, 1)
+	}
+	// Only the API response refreshes the token, not the gateway's 401.
+	if refreshes != 1 {
+		t.Fatalf("got %d token refreshes, wanted %d", refreshes, 1)
+	}
+}
+
+func TestWithGatewaysInvalidURL(t *testing.T) {
+	_, err := NewClient(WithToken(validToken), WithGateways("ftp://example.com"))
+	if err == nil {
+		t.Fatalf("gateway with unsupported scheme was accepted")
+	}
+}
@@ -3,19 +3,161 @@ package w3s
 import (
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 
 	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	"github.com/ipld/go-car"
 	w3http "github.com/web3-storage/go-w3s-client/http"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-func (c *client) Get(ctx context.Context, cid cid.Cid) (*w3http.Web3Response, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/car/%s", c.cfg.endpoint, cid), nil)
+func (c *client) Get(ctx context.Context, cid cid.Cid) (_ *w3http.Web3Response, err error) {
+	ctx, span := c.startSpan(ctx, "Get", attribute.String("cid", cid.String()))
+	defer func() { endSpan(span, err) }()
+
+	if c.cfg.cacheFirst && c.bs != nil {
+		res, err := c.getCached(ctx, cid)
+		if err != nil {
+			return nil, err
+		}
+		if res != nil {
+			span.SetAttributes(attribute.Bool("cached", true))
+			return res, nil
+		}
+	}
+
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("/car/%s", cid), nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.cfg.token))
-	req.Header.Add("X-Client", clientName)
 	res, err := c.cfg.hc.Do(req)
-	return w3http.NewWeb3Response(res, c.bsvc), err
+	if len(c.cfg.gateways) > 0 && (err != nil || res.StatusCode != http.StatusOK) {
+		if err != nil {
+			c.cfg.log.Warnf("getting %s from the API, trying gateways: %v", cid, err)
+		} else {
+			c.cfg.log.Warnf("getting %s from the API returned status %d, trying gateways", cid, res.StatusCode)
+		}
+		gres, gerr := c.getFromGateways(ctx, cid)
+		if gerr == nil {
+			if res != nil {
+				res.Body.Close()
+			}
+			span.SetAttributes(attribute.String("gateway", gres.Request.URL.Host))
+			return gres, nil
+		}
+		c.cfg.log.Errorf("getting %s from gateways: %v", cid, gerr)
+	}
+	return w3http.NewWeb3Response(res, c.blockService(), w3http.WithLogger(c.cfg.log)), err
+}
+
+// getCached returns a response serving the DAG with the passed root from the
+// configured datastore, first fetching any missing subtrees. It returns nil if
+// the root block is not held or a subtree could not be fetched.
+func (c *client) getCached(ctx context.Context, root cid.Cid) (*w3http.Web3Response, error) {
+	dag := merkledag.NewDAGService(c.bsvc)
+	missing, err := missingCids(ctx, dag, root)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) == 1 && missing[0] == root {
+		c.cfg.log.Debugf("cache miss for %s", root)
+		return nil, nil
+	}
+	if len(missing) > 0 {
+		c.cfg.log.Debugf("cache partial hit for %s, fetching %d missing subtrees", root, len(missing))
+		for _, sub := range missing {
+			err := c.fetchSubtree(ctx, sub)
+			if err != nil {
+				c.cfg.log.Warnf("fetching subtree %s of %s, falling back to full download: %v", sub, root, err)
+				return nil, nil
+			}
+		}
+	} else {
+		c.cfg.log.Debugf("cache hit for %s", root)
+	}
+
+	// The request is not sent, but is needed by the response.
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("/car/%s", root), nil)
+	if err != nil {
+		return nil, err
+	}
+	res := carResponse(ctx, req, dag, root)
+	return w3http.NewWeb3Response(res, c.bsvc, w3http.WithLogger(c.cfg.log)), nil
+}
+
+// carResponse creates a successful response to the request with a body that is
+// a CAR of the DAG with the passed root, written from the DAG service.
+func carResponse(ctx context.Context, req *http.Request, dag ipld.DAGService, root cid.Cid) *http.Response {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(car.WriteCar(ctx, dag, []cid.Cid{root}, pw))
+	}()
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/car"}},
+		Body:          pr,
+		ContentLength: -1,
+		Request:       req,
+	}
+}
+
+// missingCids walks the DAG with the passed root in the blockstore, returning
+// the roots of the subtrees that are not held.
+func missingCids(ctx context.Context, dag ipld.DAGService, root cid.Cid) ([]cid.Cid, error) {
+	var missing []cid.Cid
+	seen := cid.NewSet()
+	var walk func(c cid.Cid) error
+	walk = func(c cid.Cid) error {
+		if !seen.Visit(c) {
+			return nil
+		}
+		nd, err := dag.Get(ctx, c)
+		if ipld.IsNotFound(err) {
+			missing = append(missing, c)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, l := range nd.Links() {
+			if err := walk(l.Cid); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err := walk(root)
+	return missing, err
+}
+
+// fetchSubtree downloads the DAG with the passed root into the configured
+// datastore.
+func (c *client) fetchSubtree(ctx context.Context, root cid.Cid) error {
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("/car/%s", root), nil)
+	if err != nil {
+		return err
+	}
+	res, err := c.cfg.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return newResponseError(res)
+	}
+	_, err = car.LoadCar(ctx, c.bs, res.Body)
+	if err != nil {
+		return err
+	}
+	// Drain the body so the connection can be reused.
+	_, err = io.Copy(ioutil.Discard, res.Body)
+	return err
 }
@@ -1,17 +1,31 @@
 package w3s
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"io/fs"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"path"
+	"path/filepath"
 	"testing"
+	"testing/fstest"
 
+	bserv "github.com/ipfs/go-blockservice"
 	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	carv2 "github.com/ipld/go-car/v2"
+	carblockstore "github.com/ipld/go-car/v2/blockstore"
+	"github.com/multiformats/go-multihash"
+	w3http "github.com/web3-storage/go-w3s-client/http"
+	"github.com/web3-storage/go-w3s-client/w3stest"
 )
 
 const (
@@ -31,15 +45,15 @@ type routeMap map[string]map[string]http.HandlerFunc
 func startTestServer(t *testing.T, routes routeMap) (*http.Client, func()) {
 	mux := http.NewServeMux()
 	for path, methodHandlers := range routes {
-		for method, handler := range methodHandlers {
-			mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
-				if r.Method != method {
-					w.WriteHeader(http.StatusNotFound)
-					return
-				}
-				handler(w, r)
-			})
-		}
+		methodHandlers := methodHandlers
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			handler, ok := methodHandlers[r.Method]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			handler(w, r)
+		})
 	}
 
 	ts := httptest.NewServer(mux)
@@ -135,3 +149,181 @@ func TestGetHappyPath(t *testing.T) {
 		t.Fatalf("failed to send walk car: %v", err)
 	}
 }
+
+func TestGetCacheFirst(t *testing.T) {
+	srv := w3stest.NewServer(w3stest.WithToken(validToken))
+	defer srv.Close()
+	ctx := context.Background()
+
+	fsys := fstest.MapFS{
+		"dir/a.txt": {Data: []byte("aaa")},
+		"dir/b.txt": {Data: []byte("bbb")},
+	}
+	f, err := fsys.Open("dir")
+	if err != nil {
+		t.Fatalf("failed to open directory: %v", err)
+	}
+	roots, err := newFakeClient(t, srv).Put(ctx, f, WithFs(fsys))
+	if err != nil {
+		t.Fatalf("failed to put directory: %v", err)
+	}
+	root := roots[0]
+
+	client, err := NewClient(WithEndpoint(srv.URL), WithToken(validToken), WithDatastore(dssync.MutexWrap(ds.NewMapDatastore())), WithCacheFirst())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	read := func(name string) string {
+		res, err := client.Get(ctx, root)
+		if err != nil {
+			t.Fatalf("failed to get: %v", err)
+		}
+		if res.StatusCode != 200 {
+			t.Fatalf("got status %d, wanted %d", res.StatusCode, 200)
+		}
+		_, rfs, err := res.Files()
+		if err != nil {
+			t.Fatalf("failed to read files: %v", err)
+		}
+		b, err := fs.ReadFile(rfs, name)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		return string(b)
+	}
+
+	// A miss downloads the whole DAG, after which it is served locally.
+	for i := 0; i < 2; i++ {
+		before := srv.Requests()
+		if got := read("/a.txt"); got != "aaa" {
+			t.Fatalf("got %q, wanted %q", got, "aaa")
+		}
+		wanted := 1 - i
+		if got := srv.Requests() - before; got != wanted {
+			t.Fatalf("got %d requests for get %d, wanted %d", got, i, wanted)
+		}
+	}
+
+	// A partial hit fetches just the missing block.
+	leaf, err := cid.V1Builder{Codec: cid.Raw, MhType: multihash.SHA2_256}.Sum([]byte("bbb"))
+	if err != nil {
+		t.Fatalf("failed to compute CID: %v", err)
+	}
+	if err := client.Blockstore().DeleteBlock(ctx, leaf); err != nil {
+		t.Fatalf("failed to delete block: %v", err)
+	}
+	before := srv.Requests()
+	if got := read("/b.txt"); got != "bbb" {
+		t.Fatalf("got %q, wanted %q", got, "bbb")
+	}
+	if got := srv.Requests() - before; got != 1 {
+		t.Fatalf("got %d requests for partial hit, wanted %d", got, 1)
+	}
+	if has, _ := client.Blockstore().Has(ctx, leaf); !has {
+		t.Fatalf("missing block %s was not fetched", leaf)
+	}
+}
+
+func TestResponseRoots(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/car/"+helloRoot, nil)
+	res := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewReader(multiRootCar(t))),
+		Request:    req,
+	}
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	w3res := w3http.NewWeb3Response(res, bserv.New(bs, nil))
+
+	roots, err := w3res.Roots()
+	if err != nil {
+		t.Fatalf("failed to read roots: %v", err)
+	}
+	if len(roots) != 2 || roots[0].String() != helloRoot || roots[1].String() != thanksRoot {
+		t.Fatalf("got roots %v, wanted [%s %s]", roots, helloRoot, thanksRoot)
+	}
+
+	// The body is read once, after which files can be opened for each root.
+	_, fsys, err := w3res.FilesFor(roots[1])
+	if err != nil {
+		t.Fatalf("failed to read files: %v", err)
+	}
+	if _, err := fs.Stat(fsys, "/thanks.txt"); err != nil {
+		t.Fatalf("failed to stat file: %v", err)
+	}
+	_, fsys, err = w3res.Files()
+	if err != nil {
+		t.Fatalf("failed to read files: %v", err)
+	}
+	if _, err := fs.Stat(fsys, "/helloworld.txt"); err != nil {
+		t.Fatalf("failed to stat file: %v", err)
+	}
+
+	other, err := cid.V1Builder{Codec: cid.Raw, MhType: multihash.SHA2_256}.Sum([]byte("other"))
+	if err != nil {
+		t.Fatalf("failed to compute CID: %v", err)
+	}
+	if _, _, err := w3res.FilesFor(other); err == nil {
+		t.Fatalf("files for a CID that is not a root did not fail")
+	}
+}
+
+func TestSaveCar(t *testing.T) {
+	srv := w3stest.NewServer(w3stest.WithToken(validToken))
+	defer srv.Close()
+	client := newFakeClient(t, srv)
+	root := putFakeFile(t, client, "hello.txt", "hello")
+
+	res, err := client.Get(context.Background(), root)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "hello.car")
+	if err := res.SaveCar(path); err != nil {
+		t.Fatalf("failed to save car: %v", err)
+	}
+	// The files are still available once the body has been read.
+	_, fsys, err := res.Files()
+	if err != nil {
+		t.Fatalf("failed to read files: %v", err)
+	}
+	if _, err := fs.Stat(fsys, "/hello.txt"); err != nil {
+		t.Fatalf("failed to stat file: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open car: %v", err)
+	}
+	defer f.Close()
+	version, err := carv2.ReadVersion(f)
+	if err != nil {
+		t.Fatalf("failed to read version: %v", err)
+	}
+	if version != 2 {
+		t.Fatalf("got version %d, wanted %d", version, 2)
+	}
+
+	leaf, err := cid.V1Builder{Codec: cid.Raw, MhType: multihash.SHA2_256}.Sum([]byte("hello"))
+	if err != nil {
+		t.Fatalf("failed to compute CID: %v", err)
+	}
+	rbs, err := carblockstore.OpenReadOnly(path)
+	if err != nil {
+		t.Fatalf("failed to open blockstore: %v", err)
+	}
+	defer rbs.Close()
+	roots, err := rbs.Roots()
+	if err != nil {
+		t.Fatalf("failed to read roots: %v", err)
+	}
+	if len(roots) != 1 || roots[0] != root {
+		t.Fatalf("got roots %v, wanted [%s]", roots, root)
+	}
+	b, err := rbs.Get(context.Background(), leaf)
+	if err != nil {
+		t.Fatalf("failed to get block %s: %v", leaf, err)
+	}
+	if string(b.RawData()) != "hello" {
+		t.Fatalf("got block data %q, wanted %q", b.RawData(), "hello")
+	}
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	carblockstore "github.com/ipld/go-car/v2/blockstore"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SaveCar consumes the HTTP response and writes the DAG of each of its roots
+// to an indexed CARv2 file at path, replacing any existing file. The index
+// allows blocks to be read by CID without scanning the file, for example by
+// opening it with OpenReadOnly from github.com/ipld/go-car/v2/blockstore.
+// It may be called before or after Files.
+func (r *Web3Response) SaveCar(path string) (err error) {
+	ctx := r.Request.Context()
+	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer(instrumentationName)
+	ctx, span := tracer.Start(ctx, "w3s.SaveCar")
+	defer func() {
+		if err != nil {
+			r.log.Errorf("saving response (status %d) to %s: %v", r.StatusCode, path, err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	roots, err := r.readBlocks(ctx, span)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, as the CARv2 blockstore resumes writing
+	// to a file that already exists rather than replacing it.
+	f, err := ioutil.TempFile(filepath.Dir(path), ".w3s-*.car")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		f.Close()
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+	rw, err := carblockstore.OpenReadWriteFile(f, roots)
+	if err != nil {
+		return err
+	}
+
+	var blocks int
+	dag := merkledag.NewDAGService(r.bsvc)
+	getLinks := func(ctx context.Context, c cid.Cid) ([]*ipld.Link, error) {
+		nd, err := dag.Get(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		if err := rw.Put(ctx, nd); err != nil {
+			return nil, err
+		}
+		blocks++
+		return nd.Links(), nil
+	}
+	seen := cid.NewSet()
+	for _, root := range roots {
+		err = merkledag.Walk(ctx, getLinks, root, seen.Visit)
+		if err != nil {
+			rw.Discard()
+			return err
+		}
+	}
+	err = rw.Finalize()
+	if err != nil {
+		return err
+	}
+	err = f.Chmod(0644)
+	if err != nil {
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return err
+	}
+
+	r.log.Debugf("saved %d blocks to %s", blocks, path)
+	span.SetAttributes(attribute.String("path", path), attribute.Int("saved", blocks))
+	return nil
+}
@@ -0,0 +1,151 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	dagpb "github.com/ipld/go-codec-dagpb"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/linking"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/traversal"
+	"github.com/ipld/go-ipld-prime/traversal/selector"
+	"github.com/ipld/go-ipld-prime/traversal/selector/builder"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	// Register the codecs structured data is decoded with.
+	_ "github.com/ipld/go-ipld-prime/codec/dagcbor"
+	_ "github.com/ipld/go-ipld-prime/codec/dagjson"
+	_ "github.com/ipld/go-ipld-prime/codec/raw"
+)
+
+// ErrNotUnixFS is returned by Files and ExtractTo when the root of the
+// response is not a UnixFS file or directory. Use Dag to read it instead.
+var ErrNotUnixFS = errors.New("not UnixFS content")
+
+// Dag is an IPLD DAG read from a response, whose nodes are decoded with the
+// codec of their CID.
+type Dag struct {
+	// Root is the CID of the root node.
+	Root cid.Cid
+	// Node is the decoded root node.
+	Node datamodel.Node
+	// LinkSystem loads linked nodes from the blocks of the response.
+	LinkSystem linking.LinkSystem
+}
+
+// Dag consumes the HTTP response and returns the DAG it contains. Unlike
+// Files it accepts any content, such as DAG-CBOR or DAG-JSON data.
+func (r *Web3Response) Dag() (_ *Dag, err error) {
+	ctx := r.Request.Context()
+	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer(instrumentationName)
+	ctx, span := tracer.Start(ctx, "w3s.Dag")
+	defer func() {
+		if err != nil {
+			r.log.Errorf("reading DAG from response (status %d): %v", r.StatusCode, err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	roots, err := r.readBlocks(ctx, span)
+	if err != nil {
+		return nil, err
+	}
+	rootCid := roots[0]
+	span.SetAttributes(attribute.Int64("codec", int64(rootCid.Prefix().Codec)))
+
+	d := &Dag{Root: rootCid, LinkSystem: newLinkSystem(r.bsvc)}
+	d.Node, err = d.Load(ctx, rootCid)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// newLinkSystem creates a link system that reads blocks from the block
+// service.
+func newLinkSystem(bsvc blockservice.BlockService) linking.LinkSystem {
+	ls := cidlink.DefaultLinkSystem()
+	ls.StorageReadOpener = func(lctx linking.LinkContext, lnk datamodel.Link) (io.Reader, error) {
+		cl, ok := lnk.(cidlink.Link)
+		if !ok {
+			return nil, fmt.Errorf("unsupported link type: %T", lnk)
+		}
+		b, err := bsvc.GetBlock(lctx.Ctx, cl.Cid)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(b.RawData()), nil
+	}
+	return ls
+}
+
+// chooser returns the prototype to load a link with, which is the DAG-PB
+// schema for DAG-PB nodes and basic nodes otherwise.
+var chooser = dagpb.AddSupportToChooser(func(datamodel.Link, linking.LinkContext) (datamodel.NodePrototype, error) {
+	return basicnode.Prototype.Any, nil
+})
+
+// Load loads and decodes the node with the passed CID.
+func (d *Dag) Load(ctx context.Context, c cid.Cid) (datamodel.Node, error) {
+	lnk := cidlink.Link{Cid: c}
+	lctx := linking.LinkContext{Ctx: ctx}
+	np, err := chooser(lnk, lctx)
+	if err != nil {
+		return nil, err
+	}
+	return d.LinkSystem.Load(lctx, lnk, np)
+}
+
+// Lookup returns the node at the passed path from the root, such as
+// "entries/0/name", following any links along the way.
+func (d *Dag) Lookup(ctx context.Context, path string) (datamodel.Node, error) {
+	var found datamodel.Node
+	err := d.progress(ctx).Focus(d.Node, datamodel.ParsePath(path), func(_ traversal.Progress, n datamodel.Node) error {
+		found = n
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// Walk calls fn for every node in the DAG, starting at the root and following
+// links. The path of each node is available from the progress passed to fn.
+func (d *Dag) Walk(ctx context.Context, fn func(traversal.Progress, datamodel.Node) error) error {
+	ssb := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any)
+	spec := ssb.ExploreRecursive(selector.RecursionLimitNone(), ssb.ExploreUnion(ssb.Matcher(), ssb.ExploreAll(ssb.ExploreRecursiveEdge())))
+	sel, err := spec.Selector()
+	if err != nil {
+		return err
+	}
+	return d.progress(ctx).WalkMatching(d.Node, sel, fn)
+}
+
+func (d *Dag) progress(ctx context.Context) traversal.Progress {
+	return traversal.Progress{
+		Cfg: &traversal.Config{
+			Ctx:                            ctx,
+			LinkSystem:                     d.LinkSystem,
+			LinkTargetNodePrototypeChooser: chooser,
+		},
+	}
+}
+
+// isUnixFS reports whether the CID is of a block that may be UnixFS, which is
+// a DAG-PB node or a raw leaf.
+func isUnixFS(c cid.Cid) bool {
+	codec := c.Prefix().Codec
+	return codec == cid.DagProtobuf || codec == cid.Raw
+}
@@ -0,0 +1,535 @@
+package http
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	"github.com/ipfs/go-unixfs"
+	uio "github.com/ipfs/go-unixfs/io"
+	"github.com/ipfs/go-unixfs/pb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+)
+
+const defaultExtractConcurrency = 8
+
+// ErrUnsafePath is returned by ExtractTo when the DAG contains a name or
+// symlink that would be written outside of the destination directory.
+var ErrUnsafePath = errors.New("unsafe path")
+
+// ConflictPolicy determines what ExtractTo does when a path it is about to
+// write already exists.
+type ConflictPolicy int
+
+const (
+	// ConflictFail aborts the extraction with an error wrapping fs.ErrExist.
+	ConflictFail ConflictPolicy = iota
+	// ConflictOverwrite replaces the existing file or directory.
+	ConflictOverwrite
+	// ConflictSkip leaves the existing file or directory untouched.
+	ConflictSkip
+)
+
+type extractConfig struct {
+	concurrency  int
+	conflict     ConflictPolicy
+	restoreMode  bool
+	restoreMtime bool
+}
+
+// ExtractOption is an option configuring a call to ExtractTo.
+type ExtractOption func(cfg *extractConfig)
+
+// WithExtractConcurrency sets the maximum number of files written in
+// parallel. The default is 8.
+func WithExtractConcurrency(n int) ExtractOption {
+	return func(cfg *extractConfig) {
+		if n > 0 {
+			cfg.concurrency = n
+		}
+	}
+}
+
+// WithConflictPolicy sets what happens when a path already exists in the
+// destination directory. The default is ConflictFail.
+func WithConflictPolicy(p ConflictPolicy) ExtractOption {
+	return func(cfg *extractConfig) {
+		cfg.conflict = p
+	}
+}
+
+// WithRestoreMode sets whether file and directory permissions stored in the
+// DAG are applied. It is enabled by default.
+func WithRestoreMode(restore bool) ExtractOption {
+	return func(cfg *extractConfig) {
+		cfg.restoreMode = restore
+	}
+}
+
+// WithRestoreMtime sets whether modification times stored in the DAG are
+// applied. It is enabled by default.
+func WithRestoreMtime(restore bool) ExtractOption {
+	return func(cfg *extractConfig) {
+		cfg.restoreMtime = restore
+	}
+}
+
+// ExtractTo consumes the HTTP response and writes its content to dir, which is
+// created if it does not exist. If the root is a directory its entries are
+// written to dir, otherwise the root file is written to dir/<root CID>.
+//
+// Names that could escape dir and symlinks pointing outside of it cause an
+// error wrapping ErrUnsafePath. Existing symlinks in dir are never followed.
+func (r *Web3Response) ExtractTo(dir string, options ...ExtractOption) (err error) {
+	cfg := extractConfig{
+		concurrency:  defaultExtractConcurrency,
+		restoreMode:  true,
+		restoreMtime: true,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	ctx := r.Request.Context()
+	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer(instrumentationName)
+	ctx, span := tracer.Start(ctx, "w3s.ExtractTo")
+	defer func() {
+		if err != nil {
+			r.log.Errorf("extracting response (status %d) to %s: %v", r.StatusCode, dir, err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	roots, err := r.readBlocks(ctx, span)
+	if err != nil {
+		return err
+	}
+	rootCid := roots[0]
+	if !isUnixFS(rootCid) {
+		err = fmt.Errorf("%w: root %s has codec 0x%x", ErrNotUnixFS, rootCid, rootCid.Prefix().Codec)
+		return err
+	}
+
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(root, 0755)
+	if err != nil {
+		return err
+	}
+
+	dag := merkledag.NewDAGService(r.bsvc)
+	nd, err := dag.Get(ctx, rootCid)
+	if err != nil {
+		return err
+	}
+
+	// Symlink targets are resolved against the disk, so the root is compared
+	// with its resolved path in case it is itself under a symlink.
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(cfg.concurrency)
+	e := &extractor{cfg: cfg, dag: dag, root: root, realRoot: realRoot, group: g}
+
+	dest := root
+	if !isDir(nd) {
+		dest = filepath.Join(root, rootCid.String())
+	}
+	err = e.extract(gctx, nd, dest)
+	if werr := g.Wait(); err == nil {
+		err = werr
+	}
+	if err != nil {
+		return err
+	}
+
+	// Directories are updated last, deepest first, so that writing their
+	// entries neither fails on read-only permissions nor changes mtimes.
+	for i := len(e.dirs) - 1; i >= 0; i-- {
+		err = e.restore(e.dirs[i].path, e.dirs[i].meta)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.log.Debugf("extracted %s to %s (%d files, %d bytes)", rootCid, root, e.files, e.bytes)
+	span.SetAttributes(
+		attribute.String("cid", rootCid.String()),
+		attribute.Int64("files", e.files),
+		attribute.Int64("bytes", e.bytes),
+	)
+	return nil
+}
+
+// metadata is the optional mode and mtime of a UnixFS node.
+type metadata struct {
+	mode     os.FileMode
+	hasMode  bool
+	mtime    time.Time
+	hasMtime bool
+}
+
+type extractedDir struct {
+	path string
+	meta metadata
+}
+
+type extractor struct {
+	cfg      extractConfig
+	dag      ipld.DAGService
+	root     string
+	realRoot string
+	group    *errgroup.Group
+
+	// dirs is only accessed by the walking goroutine, files and bytes are
+	// updated atomically by the writers.
+	dirs  []extractedDir
+	files int64
+	bytes int64
+}
+
+func isDir(nd ipld.Node) bool {
+	pn, ok := nd.(*merkledag.ProtoNode)
+	if !ok {
+		return false
+	}
+	fsn, err := unixfs.FSNodeFromBytes(pn.Data())
+	return err == nil && fsn.IsDir()
+}
+
+func (e *extractor) extract(ctx context.Context, nd ipld.Node, dest string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !e.within(dest) {
+		return fmt.Errorf("%w: %s", ErrUnsafePath, dest)
+	}
+
+	pn, ok := nd.(*merkledag.ProtoNode)
+	if !ok {
+		if _, ok := nd.(*merkledag.RawNode); ok {
+			return e.writeFile(ctx, nd, dest, metadata{})
+		}
+		return fmt.Errorf("unsupported node %s at %s", nd.Cid(), dest)
+	}
+	fsn, err := unixfs.FSNodeFromBytes(pn.Data())
+	if err != nil {
+		return fmt.Errorf("decoding %s: %w", nd.Cid(), err)
+	}
+	meta, err := readMetadata(pn.Data())
+	if err != nil {
+		return fmt.Errorf("decoding %s: %w", nd.Cid(), err)
+	}
+
+	switch fsn.Type() {
+	case unixfs_pb.Data_Directory, unixfs_pb.Data_HAMTShard:
+		return e.extractDir(ctx, pn, dest, meta)
+	case unixfs_pb.Data_File, unixfs_pb.Data_Raw:
+		return e.writeFile(ctx, nd, dest, meta)
+	case unixfs_pb.Data_Symlink:
+		return e.writeSymlink(string(fsn.Data()), dest)
+	default:
+		return fmt.Errorf("unsupported UnixFS type %s at %s", fsn.Type(), dest)
+	}
+}
+
+func (e *extractor) extractDir(ctx context.Context, nd *merkledag.ProtoNode, dest string, meta metadata) error {
+	if dest != e.root {
+		ok, err := e.prepare(dest, true)
+		if err != nil || !ok {
+			return err
+		}
+	}
+	e.dirs = append(e.dirs, extractedDir{dest, meta})
+
+	dir, err := uio.NewDirectoryFromNode(e.dag, nd)
+	if err != nil {
+		return err
+	}
+	return dir.ForEachLink(ctx, func(l *ipld.Link) error {
+		if !validName(l.Name) {
+			return fmt.Errorf("%w: invalid name %q in %s", ErrUnsafePath, l.Name, dest)
+		}
+		child, err := l.GetNode(ctx, e.dag)
+		if err != nil {
+			return err
+		}
+		return e.extract(ctx, child, filepath.Join(dest, l.Name))
+	})
+}
+
+// prepare applies the conflict policy to dest, reporting whether it should be
+// written. An existing directory is reused when dir is true.
+func (e *extractor) prepare(dest string, dir bool) (bool, error) {
+	fi, err := os.Lstat(dest)
+	if errors.Is(err, fs.ErrNotExist) {
+		if dir {
+			return true, os.Mkdir(dest, 0755)
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if dir && fi.IsDir() {
+		return true, nil
+	}
+
+	switch e.cfg.conflict {
+	case ConflictSkip:
+		return false, nil
+	case ConflictOverwrite:
+		err = os.RemoveAll(dest)
+		if err != nil {
+			return false, err
+		}
+		if dir {
+			return true, os.Mkdir(dest, 0755)
+		}
+		return true, nil
+	default:
+		return false, fmt.Errorf("extracting %s: %w", dest, fs.ErrExist)
+	}
+}
+
+func (e *extractor) writeFile(ctx context.Context, nd ipld.Node, dest string, meta metadata) error {
+	ok, err := e.prepare(dest, false)
+	if err != nil || !ok {
+		return err
+	}
+	e.group.Go(func() error {
+		rd, err := uio.NewDagReader(ctx, nd, e.dag)
+		if err != nil {
+			return err
+		}
+		// O_EXCL ensures a symlink created in the meantime is not followed.
+		f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return err
+		}
+		n, err := io.Copy(f, rd)
+		atomic.AddInt64(&e.files, 1)
+		atomic.AddInt64(&e.bytes, n)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		return e.restore(dest, meta)
+	})
+	return nil
+}
+
+func (e *extractor) writeSymlink(target, dest string) error {
+	if target == "" || filepath.IsAbs(target) || !e.resolvesWithin(filepath.Dir(dest), target) {
+		return fmt.Errorf("%w: symlink %s points to %q", ErrUnsafePath, dest, target)
+	}
+	ok, err := e.prepare(dest, false)
+	if err != nil || !ok {
+		return err
+	}
+	return os.Symlink(target, dest)
+}
+
+// restore applies the mode and mtime stored in the DAG, as configured.
+func (e *extractor) restore(path string, meta metadata) error {
+	if e.cfg.restoreMode && meta.hasMode {
+		err := os.Chmod(path, meta.mode)
+		if err != nil {
+			return err
+		}
+	}
+	if e.cfg.restoreMtime && meta.hasMtime {
+		err := os.Chtimes(path, meta.mtime, meta.mtime)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// within reports whether path is the root directory or inside it.
+func (e *extractor) within(path string) bool {
+	return inside(e.root, path)
+}
+
+// resolvesWithin reports whether the relative symlink target, followed from
+// dir, stays inside the root. Each element is resolved against the disk, so
+// symlinks already extracted are followed rather than read lexically. A ".."
+// after an element that does not exist yet is rejected, as that element may
+// later be extracted as a symlink.
+func (e *extractor) resolvesWithin(dir, target string) bool {
+	path, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+	pending := false
+	for _, name := range strings.Split(filepath.ToSlash(target), "/") {
+		switch {
+		case name == "" || name == ".":
+			continue
+		case name == "..":
+			if pending {
+				return false
+			}
+			path = filepath.Dir(path)
+		case pending:
+			path = filepath.Join(path, name)
+		default:
+			next := filepath.Join(path, name)
+			fi, err := os.Lstat(next)
+			switch {
+			case err != nil:
+				pending = true
+			case fi.Mode()&os.ModeSymlink != 0:
+				next, err = filepath.EvalSymlinks(next)
+				if err != nil {
+					return false
+				}
+			case !fi.IsDir():
+				pending = true
+			}
+			path = next
+		}
+		if !inside(e.realRoot, path) {
+			return false
+		}
+	}
+	return true
+}
+
+// inside reports whether path is root or inside it.
+func inside(root, path string) bool {
+	path = filepath.Clean(path)
+	return path == root || strings.HasPrefix(path, root+string(filepath.Separator))
+}
+
+// validName reports whether name is safe to use as a single path element.
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\\x00")
+}
+
+// UnixFS 1.5 metadata fields, which go-unixfs does not decode.
+const (
+	fieldMode  = 7
+	fieldMtime = 8
+
+	fieldMtimeSeconds = 1
+	fieldMtimeNanos   = 2
+)
+
+var errMalformed = errors.New("malformed UnixFS data")
+
+// readMetadata reads the mode and mtime from encoded UnixFS data, if present.
+func readMetadata(data []byte) (metadata, error) {
+	var meta metadata
+	err := readFields(data, func(field int, v uint64, b []byte) error {
+		switch field {
+		case fieldMode:
+			meta.mode = unixMode(uint32(v))
+			meta.hasMode = true
+		case fieldMtime:
+			var secs int64
+			var nanos uint32
+			err := readFields(b, func(field int, v uint64, _ []byte) error {
+				switch field {
+				case fieldMtimeSeconds:
+					secs = int64(v)
+				case fieldMtimeNanos:
+					nanos = uint32(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			meta.mtime = time.Unix(secs, int64(nanos))
+			meta.hasMtime = true
+		}
+		return nil
+	})
+	return meta, err
+}
+
+// readFields calls f with each field of a protobuf message. Varint and fixed
+// size values are passed in v, length delimited values in b.
+func readFields(data []byte, f func(field int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errMalformed
+		}
+		data = data[n:]
+
+		var v uint64
+		var b []byte
+		switch key & 7 {
+		case 0:
+			v, n = binary.Uvarint(data)
+			if n <= 0 {
+				return errMalformed
+			}
+			data = data[n:]
+		case 1:
+			if len(data) < 8 {
+				return errMalformed
+			}
+			v, data = binary.LittleEndian.Uint64(data), data[8:]
+		case 2:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return errMalformed
+			}
+			b, data = data[n:n+int(l)], data[n+int(l):]
+		case 5:
+			if len(data) < 4 {
+				return errMalformed
+			}
+			v, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		default:
+			return errMalformed
+		}
+
+		err := f(int(key>>3), v, b)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unixMode converts UnixFS permission bits to an os.FileMode.
+func unixMode(m uint32) os.FileMode {
+	mode := os.FileMode(m & 0777)
+	if m&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if m&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if m&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
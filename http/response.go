@@ -2,52 +2,125 @@ package http
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
+	"sync"
 
 	"github.com/ipfs/go-blockservice"
-	"github.com/ipld/go-car"
+	"github.com/ipfs/go-cid"
+	carv2 "github.com/ipld/go-car/v2"
 	"github.com/web3-storage/go-w3s-client/fs/adapter"
+	"github.com/web3-storage/go-w3s-client/logging"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const instrumentationName = "github.com/web3-storage/go-w3s-client"
+
 // Web3Response is a response to a call to the Get method.
 type Web3Response struct {
 	*http.Response
 	bsvc blockservice.BlockService
+	log  logging.Logger
+
+	// mu guards the result of reading the body, which is only read once.
+	mu      sync.Mutex
+	read    bool
+	roots   []cid.Cid
+	readErr error
+}
+
+// Option is an option configuring a Web3Response.
+type Option func(r *Web3Response)
+
+// WithLogger sets the logger used to report progress and failures.
+func WithLogger(log logging.Logger) Option {
+	return func(r *Web3Response) {
+		if log != nil {
+			r.log = log
+		}
+	}
 }
 
-func NewWeb3Response(r *http.Response, bsvc blockservice.BlockService) *Web3Response {
-	return &Web3Response{r, bsvc}
+func NewWeb3Response(r *http.Response, bsvc blockservice.BlockService, options ...Option) *Web3Response {
+	res := &Web3Response{Response: r, bsvc: bsvc, log: logging.Nop}
+	for _, opt := range options {
+		opt(res)
+	}
+	return res
 }
 
 // Files consumes the HTTP response and returns the root file (which may be a
 // directory). You can use the returned FileSystem implementation to read
 // nested files and directories if the returned file is a directory.
-func (r *Web3Response) Files() (fs.File, fs.FS, error) {
-	cr, err := car.NewCarReader(r.Body)
+// ErrNotUnixFS is returned if the content is not UnixFS, see Dag. If the CAR
+// has several roots the first is returned, see FilesFor.
+func (r *Web3Response) Files() (_ fs.File, _ fs.FS, err error) {
+	ctx := r.Request.Context()
+	// Use the tracer provider of the span that made the request, if any.
+	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer(instrumentationName)
+	ctx, span := tracer.Start(ctx, "w3s.Files")
+	defer func() {
+		if err != nil {
+			r.log.Errorf("reading files from response (status %d): %v", r.StatusCode, err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	roots, err := r.readBlocks(ctx, span)
 	if err != nil {
 		return nil, nil, err
 	}
+	return r.openFiles(ctx, roots[0])
+}
 
-	for {
-		b, err := cr.Next()
+// FilesFor consumes the HTTP response and returns the file or directory with
+// the passed root, which must be one of Roots. It may be called for each root.
+func (r *Web3Response) FilesFor(root cid.Cid) (_ fs.File, _ fs.FS, err error) {
+	ctx := r.Request.Context()
+	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer(instrumentationName)
+	ctx, span := tracer.Start(ctx, "w3s.FilesFor", trace.WithAttributes(attribute.String("root", root.String())))
+	defer func() {
 		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return nil, nil, err
+			r.log.Errorf("reading files for %s from response (status %d): %v", root, r.StatusCode, err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
 		}
-		err = r.bsvc.AddBlock(context.Background(), b)
-		if err != nil {
-			return nil, nil, err
+		span.End()
+	}()
+
+	roots, err := r.readBlocks(ctx, span)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, c := range roots {
+		if c.Equals(root) {
+			return r.openFiles(ctx, root)
 		}
 	}
+	err = fmt.Errorf("%s is not a root of the response", root)
+	return nil, nil, err
+}
 
+// Roots consumes the HTTP response and returns the roots of the CAR it
+// contains, of which there is usually one.
+func (r *Web3Response) Roots() ([]cid.Cid, error) {
 	ctx := r.Request.Context()
-	rootCid := cr.Header.Roots[0]
+	return r.readBlocks(ctx, trace.SpanFromContext(ctx))
+}
+
+func (r *Web3Response) openFiles(ctx context.Context, root cid.Cid) (fs.File, fs.FS, error) {
+	if !isUnixFS(root) {
+		return nil, nil, fmt.Errorf("%w: root %s has codec 0x%x", ErrNotUnixFS, root, root.Prefix().Codec)
+	}
 
-	fs, err := adapter.NewFsWithContext(ctx, rootCid, r.bsvc)
+	fs, err := adapter.NewFsWithContext(ctx, root, r.bsvc)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -59,3 +132,54 @@ func (r *Web3Response) Files() (fs.File, fs.FS, error) {
 
 	return f, fs, nil
 }
+
+// readBlocks reads the CAR in the response body, which may be a CARv1 or a
+// CARv2, into the block service, returning its roots. The body is read and
+// closed on the first call, and later calls return the same result.
+func (r *Web3Response) readBlocks(ctx context.Context, span trace.Span) ([]cid.Cid, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.read {
+		r.roots, r.readErr = r.readCar(ctx, span)
+		r.Body.Close()
+		r.read = true
+	}
+	return r.roots, r.readErr
+}
+
+func (r *Web3Response) readCar(ctx context.Context, span trace.Span) ([]cid.Cid, error) {
+	cr, err := carv2.NewBlockReader(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(cr.Roots) == 0 {
+		return nil, errors.New("CAR has no roots")
+	}
+
+	var blocks, size int
+	for {
+		b, err := cr.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		err = r.bsvc.AddBlock(ctx, b)
+		if err != nil {
+			return nil, err
+		}
+		blocks++
+		size += len(b.RawData())
+	}
+
+	roots := cr.Roots
+	r.log.Debugf("read %d blocks (%d bytes) from CAR with roots %v", blocks, size, roots)
+	span.SetAttributes(
+		attribute.String("cid", roots[0].String()),
+		attribute.Int("roots", len(roots)),
+		attribute.Int("blocks", blocks),
+		attribute.Int("bytes", size),
+	)
+	return roots, nil
+}
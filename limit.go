@@ -0,0 +1,175 @@
+package w3s
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// limitConfig configures the rate and concurrency limits for requests. A zero
+// value for a field means no limit of that kind is set.
+type limitConfig struct {
+	rate        rate.Limit
+	burst       int
+	concurrency int
+}
+
+// endpointPattern is a pattern matching requests to an API endpoint, of the
+// form "[METHOD ]/path/prefix" e.g. "POST /car" or "/status/".
+type endpointPattern struct {
+	method string
+	prefix string
+}
+
+func parseEndpointPattern(s string) endpointPattern {
+	if i := strings.IndexByte(s, ' '); i > 0 {
+		return endpointPattern{method: strings.ToUpper(s[:i]), prefix: strings.TrimSpace(s[i+1:])}
+	}
+	return endpointPattern{prefix: s}
+}
+
+func (p endpointPattern) matches(method, path string) bool {
+	return (p.method == "" || p.method == method) && strings.HasPrefix(path, p.prefix)
+}
+
+// moreSpecific reports whether p should take precedence over o when both match
+// a request.
+func (p endpointPattern) moreSpecific(o endpointPattern) bool {
+	if len(p.prefix) != len(o.prefix) {
+		return len(p.prefix) > len(o.prefix)
+	}
+	return p.method != "" && o.method == ""
+}
+
+// limiter is a rate limiter and semaphore applied to a set of requests.
+type limiter struct {
+	rate *rate.Limiter
+	sem  chan struct{}
+}
+
+// acquire waits for a request to be permitted by the limiter. If it returns
+// nil then release must be called once the request has completed.
+func (l *limiter) acquire(ctx context.Context) error {
+	if l.rate != nil {
+		if err := l.rate.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (l *limiter) release() {
+	if l.sem != nil {
+		<-l.sem
+	}
+}
+
+type endpointLimiter struct {
+	pattern endpointPattern
+	*limiter
+}
+
+// limitTransport applies rate and concurrency limits to requests. Requests
+// hold their concurrency slot until the response body is closed.
+type limitTransport struct {
+	next      http.RoundTripper
+	basePath  string
+	defaults  *limiter
+	endpoints []endpointLimiter
+}
+
+// newLimitTransport creates a transport enforcing the passed limits. Endpoint
+// overrides replace the default rate limit and/or concurrency limit for
+// matching requests - a limit not set in an override is shared with requests
+// to other endpoints.
+func newLimitTransport(next http.RoundTripper, basePath string, defaults limitConfig, endpoints map[string]limitConfig) http.RoundTripper {
+	def := newLimiter(defaults, nil)
+	if def.rate == nil && def.sem == nil && len(endpoints) == 0 {
+		return next
+	}
+	t := limitTransport{next: next, basePath: strings.TrimSuffix(basePath, "/"), defaults: def}
+	for pattern, cfg := range endpoints {
+		t.endpoints = append(t.endpoints, endpointLimiter{parseEndpointPattern(pattern), newLimiter(cfg, def)})
+	}
+	return t
+}
+
+func newLimiter(cfg limitConfig, fallback *limiter) *limiter {
+	l := limiter{}
+	if cfg.rate > 0 {
+		burst := cfg.burst
+		if burst < 1 {
+			burst = 1
+		}
+		l.rate = rate.NewLimiter(cfg.rate, burst)
+	} else if fallback != nil {
+		l.rate = fallback.rate
+	}
+	if cfg.concurrency > 0 {
+		l.sem = make(chan struct{}, cfg.concurrency)
+	} else if fallback != nil {
+		l.sem = fallback.sem
+	}
+	return &l
+}
+
+func (t limitTransport) limiterFor(req *http.Request) *limiter {
+	path := strings.TrimPrefix(req.URL.Path, t.basePath)
+	var match *endpointLimiter
+	for i, e := range t.endpoints {
+		if e.pattern.matches(req.Method, path) && (match == nil || e.pattern.moreSpecific(match.pattern)) {
+			match = &t.endpoints[i]
+		}
+	}
+	if match != nil {
+		return match.limiter
+	}
+	return t.defaults
+}
+
+func (t limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	l := t.limiterFor(req)
+	if err := l.acquire(req.Context()); err != nil {
+		return nil, err
+	}
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		l.release()
+		return nil, err
+	}
+	res.Body = &releaseBody{ReadCloser: res.Body, release: l.release}
+	return res, nil
+}
+
+// releaseBody calls release once, when the body is read to the end or
+// closed, so a body that is consumed but never closed does not hold a slot.
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.release)
+	}
+	return n, err
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,164 @@
+package w3s
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+)
+
+// concurrencyHandler wraps a handler, recording the maximum number of requests
+// it served at once.
+type concurrencyHandler struct {
+	mu       sync.Mutex
+	inflight int
+	max      int
+	next     http.HandlerFunc
+}
+
+func (h *concurrencyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	h.inflight++
+	if h.inflight > h.max {
+		h.max = h.inflight
+	}
+	h.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	h.next(w, r)
+	h.mu.Lock()
+	h.inflight--
+	h.mu.Unlock()
+}
+
+func statusConcurrently(t *testing.T, client Client, n int) {
+	c, err := cid.Parse(helloRoot)
+	if err != nil {
+		t.Fatalf("failed to parse cid: %v", err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Status(context.Background(), c)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("failed to get status: %v", err)
+		}
+	}
+}
+
+func TestMaxConcurrentRequests(t *testing.T) {
+	h := &concurrencyHandler{next: statusHelloCarHandler}
+	routes := routeMap{
+		"/status/" + helloRoot: {
+			http.MethodGet: h.ServeHTTP,
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken), WithMaxConcurrentRequests(2))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	statusConcurrently(t, client, 6)
+
+	if h.max > 2 {
+		t.Fatalf("got %d concurrent requests, wanted at most %d", h.max, 2)
+	}
+}
+
+func TestMaxConcurrentRequestsReleasedAfterFiles(t *testing.T) {
+	routes := routeMap{
+		"/car/" + helloRoot: {
+			http.MethodGet: getHelloCarHandler,
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken), WithMaxConcurrentRequests(2))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	root, _ := cid.Parse(helloRoot)
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		res, err := client.Get(ctx, root)
+		if err != nil {
+			cancel()
+			t.Fatalf("get %d failed: %v", i, err)
+		}
+		_, _, err = res.Files()
+		cancel()
+		if err != nil {
+			t.Fatalf("failed to read files of get %d: %v", i, err)
+		}
+	}
+}
+
+func TestEndpointMaxConcurrentRequests(t *testing.T) {
+	h := &concurrencyHandler{next: statusHelloCarHandler}
+	routes := routeMap{
+		"/status/" + helloRoot: {
+			http.MethodGet: h.ServeHTTP,
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(
+		WithHTTPClient(hc),
+		WithToken(validToken),
+		WithMaxConcurrentRequests(4),
+		WithEndpointMaxConcurrentRequests("GET /status/", 1),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	statusConcurrently(t, client, 4)
+
+	if h.max != 1 {
+		t.Fatalf("got %d concurrent requests, wanted %d", h.max, 1)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	routes := routeMap{
+		"/status/" + helloRoot: {
+			http.MethodGet: statusHelloCarHandler,
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken), WithRateLimit(20, 1))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	start := time.Now()
+	statusConcurrently(t, client, 3)
+
+	// The first request uses the burst, the remaining 2 wait 50ms each.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("got 3 requests in %v, wanted at least %v", elapsed, 90*time.Millisecond)
+	}
+}
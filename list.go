@@ -2,61 +2,248 @@ package w3s
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/tomnomnom/linkheader"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const maxPageSize = 100
 
+// Values accepted by WithSortBy.
+const (
+	SortByDate = "date"
+	SortByName = "name"
+)
+
+// Values accepted by WithSortOrder.
+const (
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+)
+
+// UploadIterator iterates over the uploads returned by a call to List. The next
+// page of results is fetched in the background while the current page is
+// consumed. The resources used for this are released when iteration reaches
+// the end or fails, so Close must be called if iteration is abandoned early.
 type UploadIterator struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
 	paginator *pageIterator
 	max       int
 	count     int
 	page      []*Status
+	err       error
+	// pageURL is the URL the current page was fetched from and offset is the
+	// number of items from that page that have been consumed. Together they
+	// form the resume cursor.
+	pageURL string
+	offset  int
+	// skip is the number of items to discard from the first page fetched, set
+	// when resuming from a cursor.
+	skip int
+}
+
+func newUploadIterator(ctx context.Context, paginator *pageIterator, max int) *UploadIterator {
+	ctx, cancel := context.WithCancel(ctx)
+	if max > 0 {
+		paginator.limit = max
+	}
+	return &UploadIterator{
+		ctx:       ctx,
+		cancel:    cancel,
+		paginator: paginator,
+		max:       max,
+	}
+}
+
+// NewUploadIterator creates an iterator over a fixed list of uploads. It is
+// intended for use by fakes of the Client interface.
+func NewUploadIterator(items []*Status) *UploadIterator {
+	pending := make(chan *uploadsPage, 1)
+	pending <- &uploadsPage{items: items}
+	return newUploadIterator(context.Background(), &pageIterator{pending: pending}, 0)
 }
 
-// Next retrieves status information for the next upload in the list.
-func (li *UploadIterator) Next() (*Status, error) {
+// Next retrieves status information for the next upload in the list, making
+// any request for the next page with the passed context. It returns io.EOF
+// when there are no more uploads.
+func (li *UploadIterator) Next(ctx context.Context) (*Status, error) {
+	if li.err != nil {
+		return nil, li.err
+	}
+	if li.max > 0 && li.count >= li.max {
+		li.cancel()
+		return nil, io.EOF
+	}
+	if len(li.page) == 0 {
+		if err := li.fill(ctx); err != nil {
+			return nil, err
+		}
+	}
 	li.count++
-	if li.max > 0 && li.count > li.max {
+	return li.shift(), nil
+}
+
+// NextPage retrieves the remaining items of the current page of results, or
+// the next page if the current page has been consumed. It returns io.EOF when
+// there are no more uploads.
+func (li *UploadIterator) NextPage(ctx context.Context) ([]*Status, error) {
+	if li.err != nil {
+		return nil, li.err
+	}
+	if li.max > 0 && li.count >= li.max {
+		li.cancel()
 		return nil, io.EOF
 	}
-	if len(li.page) > 0 {
-		item := li.page[0]
-		li.page = li.page[1:]
-		return item, nil
+	if len(li.page) == 0 {
+		if err := li.fill(ctx); err != nil {
+			return nil, err
+		}
 	}
-	res, err := li.paginator.Next()
-	if err != nil {
-		return nil, err
+	n := len(li.page)
+	if li.max > 0 && li.count+n > li.max {
+		n = li.max - li.count
 	}
-	var page []*Status
-	d := json.NewDecoder(res.Body)
-	err = d.Decode(&page)
+	items := li.page[:n]
+	li.page = li.page[n:]
+	li.offset += n
+	li.count += n
+	return items, nil
+}
+
+// All returns a channel that receives every remaining upload in the list. The
+// channel is closed when iteration completes, fails or the passed context is
+// canceled. Call Err after the channel is closed to check for failure.
+func (li *UploadIterator) All(ctx context.Context) <-chan *Status {
+	ch := make(chan *Status)
+	go func() {
+		defer close(ch)
+		for {
+			item, err := li.Next(ctx)
+			if err != nil {
+				// Record cancellation while a page was being fetched, so the
+				// listing is not mistaken for a complete one.
+				if err != io.EOF && ctx.Err() != nil {
+					li.err = ctx.Err()
+				}
+				return
+			}
+			select {
+			case ch <- item:
+			case <-ctx.Done():
+				li.err = ctx.Err()
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// Err returns the error that caused iteration to stop, if any. It returns nil
+// when iteration completed successfully.
+func (li *UploadIterator) Err() error {
+	return li.err
+}
+
+// Close stops iteration and cancels any in-flight request for the next page.
+func (li *UploadIterator) Close() error {
+	li.cancel()
+	return nil
+}
+
+// fill sets the current page to the next non-empty page of results.
+func (li *UploadIterator) fill(ctx context.Context) error {
+	for {
+		p, err := li.paginator.Next(ctx, li.ctx)
+		if err != nil {
+			// Failures caused by the context passed to this call are not
+			// sticky, the call may be retried with a new context.
+			if err != io.EOF && ctx.Err() == nil {
+				li.err = err
+			}
+			if err == io.EOF || li.err != nil {
+				li.cancel()
+			}
+			return err
+		}
+		li.pageURL = p.url
+		li.offset = 0
+		items := p.items
+		if li.skip > 0 {
+			n := li.skip
+			if n > len(items) {
+				n = len(items)
+			}
+			items = items[n:]
+			li.offset = n
+			li.skip = 0
+		}
+		li.page = items
+		if len(li.page) > 0 {
+			return nil
+		}
+		// An empty page may be followed by more results when a page was
+		// entirely skipped by a cursor.
+	}
+}
+
+func (li *UploadIterator) shift() *Status {
+	item := li.page[0]
+	li.page = li.page[1:]
+	li.offset++
+	return item
+}
+
+// Cursor returns an opaque token identifying the position of the iterator. It
+// can be passed to List using WithCursor to resume iteration after the last
+// item returned by Next. The cursor is empty if no items have been retrieved.
+func (li *UploadIterator) Cursor() string {
+	if li.pageURL == "" {
+		return ""
+	}
+	raw := fmt.Sprintf("%d,%s", li.offset, li.pageURL)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseCursor(cursor string) (string, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
-		return nil, err
+		return "", 0, fmt.Errorf("decoding cursor: %w", err)
+	}
+	parts := strings.SplitN(string(raw), ",", 2)
+	if len(parts) != 2 || !strings.HasPrefix(parts[1], "/") {
+		return "", 0, errors.New("malformed cursor")
 	}
-	li.page = page
-	if len(li.page) > 0 {
-		item := li.page[0]
-		li.page = li.page[1:]
-		return item, nil
+	offset, err := strconv.Atoi(parts[0])
+	if err != nil || offset < 0 {
+		return "", 0, errors.New("malformed cursor offset")
 	}
-	return nil, io.EOF
+	return parts[1], offset, nil
 }
 
 type listConfig struct {
 	before     time.Time
+	after      time.Time
+	sortBy     string
+	sortOrder  string
+	name       string
+	cursor     string
 	maxResults int
 }
 
-// List retrieves the list of uploads to Web3.Storage.
+// List retrieves the list of uploads to Web3.Storage. Pages are requested with
+// the context passed to the methods of the iterator, while the passed context
+// bounds the prefetching of pages in the background.
 func (c *client) List(ctx context.Context, options ...ListOption) (*UploadIterator, error) {
 	var cfg listConfig
 	for _, opt := range options {
@@ -65,79 +252,152 @@ func (c *client) List(ctx context.Context, options ...ListOption) (*UploadIterat
 		}
 	}
 
-	fetchNextPage := func(url string) (*http.Response, error) {
-		req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s%s", c.cfg.endpoint, url), nil)
+	fetchNextPage := func(ctx context.Context, url string) (_ *http.Response, err error) {
+		ctx, span := c.startSpan(ctx, "List.page", attribute.String("url", url))
+		defer func() {
+			// On success the span ends when the response body is closed.
+			if err != nil {
+				endSpan(span, err)
+			}
+		}()
+
+		req, err := c.newRequest(ctx, "GET", url, nil)
 		if err != nil {
 			return nil, err
 		}
-		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.cfg.token))
 		req.Header.Add("Access-Control-Request-Headers", "Link")
-		req.Header.Add("X-Client", clientName)
 		res, err := c.cfg.hc.Do(req)
 		if err != nil {
 			return nil, err
 		}
 		if res.StatusCode != 200 {
-			return nil, fmt.Errorf("unexpected response status: %d", res.StatusCode)
+			defer res.Body.Close()
+			return nil, newResponseError(res)
 		}
+		res.Body = &spanBody{res.Body, span}
 		return res, nil
 	}
 
-	var before string
+	if cfg.cursor != "" {
+		urlPath, offset, err := parseCursor(cfg.cursor)
+		if err != nil {
+			return nil, err
+		}
+		it := newUploadIterator(ctx, newPageIterator(urlPath, fetchNextPage), cfg.maxResults)
+		it.skip = offset
+		if it.paginator.limit > 0 {
+			it.paginator.limit += offset
+		}
+		return it, nil
+	}
+
+	query := url.Values{}
 	if cfg.before.IsZero() {
-		before = time.Now().Format(iso8601)
+		query.Set("before", time.Now().Format(iso8601))
 	} else {
-		before = cfg.before.Format(iso8601)
+		query.Set("before", cfg.before.Format(iso8601))
+	}
+	if !cfg.after.IsZero() {
+		query.Set("after", cfg.after.Format(iso8601))
+	}
+	if cfg.sortBy != "" {
+		query.Set("sortBy", cfg.sortBy)
+	}
+	if cfg.sortOrder != "" {
+		query.Set("sortOrder", cfg.sortOrder)
+	}
+	if cfg.name != "" {
+		query.Set("name", cfg.name)
 	}
 
 	size := cfg.maxResults
 	if size > maxPageSize {
 		size = maxPageSize
 	}
-
-	var urlPath string
-	if size <= 0 {
-		urlPath = fmt.Sprintf("/user/uploads?before=%s", url.QueryEscape(before))
-	} else {
-		urlPath = fmt.Sprintf("/user/uploads?before=%s&size=%d", url.QueryEscape(before), size)
+	if size > 0 {
+		query.Set("size", strconv.Itoa(size))
 	}
 
-	return &UploadIterator{
-		paginator: newPageIterator(urlPath, fetchNextPage),
-		max:       cfg.maxResults,
-	}, nil
+	return newUploadIterator(ctx, newPageIterator("/user/uploads?"+query.Encode(), fetchNextPage), cfg.maxResults), nil
+}
+
+type uploadsPage struct {
+	url   string
+	items []*Status
+	next  string
+	err   error
 }
 
 type pageIterator struct {
 	nextURL       string
-	fetchNextPage func(string) (*http.Response, error)
+	fetchNextPage func(context.Context, string) (*http.Response, error)
+	// pending receives the next page when it is being fetched in the
+	// background.
+	pending chan *uploadsPage
+	// fetched is the total number of items in the pages fetched so far. No
+	// more pages are prefetched once it reaches limit (if limit is non-zero).
+	fetched int
+	limit   int
 }
 
-func newPageIterator(url string, fetchNextPage func(string) (*http.Response, error)) *pageIterator {
+func newPageIterator(url string, fetchNextPage func(context.Context, string) (*http.Response, error)) *pageIterator {
 	return &pageIterator{
 		nextURL:       url,
 		fetchNextPage: fetchNextPage,
 	}
 }
 
-func (pi *pageIterator) Next() (*http.Response, error) {
-	if pi.nextURL == "" {
-		return nil, io.EOF
+// Next retrieves the next page of results, waiting on ctx. The page that
+// follows is requested in the background using bgctx.
+func (pi *pageIterator) Next(ctx context.Context, bgctx context.Context) (*uploadsPage, error) {
+	var p *uploadsPage
+	if pi.pending != nil {
+		select {
+		case p = <-pi.pending:
+			pi.pending = nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	} else {
+		if pi.nextURL == "" {
+			return nil, io.EOF
+		}
+		p = pi.fetch(ctx, pi.nextURL)
+	}
+	if p.err != nil {
+		return nil, p.err
 	}
-	res, err := pi.fetchNextPage(pi.nextURL)
+	pi.fetched += len(p.items)
+	pi.nextURL = p.next
+	if pi.nextURL != "" && (pi.limit <= 0 || pi.fetched < pi.limit) {
+		pending := make(chan *uploadsPage, 1)
+		go func(url string) {
+			pending <- pi.fetch(bgctx, url)
+		}(pi.nextURL)
+		pi.pending = pending
+	}
+	return p, nil
+}
+
+func (pi *pageIterator) fetch(ctx context.Context, url string) *uploadsPage {
+	p := uploadsPage{url: url}
+	res, err := pi.fetchNextPage(ctx, url)
 	if err != nil {
-		return nil, err
+		p.err = err
+		return &p
 	}
+	defer res.Body.Close()
 	linkHdrs := res.Header["Link"]
-	pi.nextURL = ""
 	if len(linkHdrs) > 0 {
 		links := linkheader.Parse(linkHdrs[0])
 		for _, l := range links {
 			if l.Rel == "next" {
-				pi.nextURL = l.URL
+				p.next = l.URL
 				break
 			}
 		}
 	}
-	return res, nil
+	d := json.NewDecoder(res.Body)
+	p.err = d.Decode(&p.items)
+	return &p
 }
@@ -0,0 +1,322 @@
+package w3s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+var uploadsPages = map[string][]map[string]interface{}{
+	"": {
+		{"cid": helloRoot, "name": "hello", "dagSize": 208, "created": "2022-02-20T15:04:05.999Z", "pins": []interface{}{}, "deals": []interface{}{}},
+		{"cid": thanksRoot, "dagSize": 218, "created": "2022-02-19T15:04:05.999Z", "pins": []interface{}{}, "deals": []interface{}{}},
+	},
+	"2": {
+		{"cid": helloRoot, "dagSize": 208, "created": "2022-02-18T15:04:05.999Z", "pins": []interface{}{}, "deals": []interface{}{}},
+	},
+}
+
+var uploadsHandler = func(w http.ResponseWriter, r *http.Request) {
+	if !hasValidToken(w, r) {
+		return
+	}
+
+	page := r.URL.Query().Get("page")
+	if page == "" {
+		w.Header().Set("Link", `</user/uploads?page=2&sortBy=Name>; rel="next"`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(uploadsPages[page])
+}
+
+func TestListHappyPath(t *testing.T) {
+	var query map[string][]string
+	routes := routeMap{
+		"/user/uploads": {
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) {
+				if query == nil {
+					query = r.URL.Query()
+				}
+				uploadsHandler(w, r)
+			},
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	it, err := client.List(context.Background(), WithSortBy(SortByName), WithSortOrder(SortOrderAsc), WithNameFilter("hello"))
+	if err != nil {
+		t.Fatalf("failed to create iterator: %v", err)
+	}
+
+	var n int
+	for {
+		s, err := it.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to get next upload: %v", err)
+		}
+		if n == 0 && s.Name != "hello" {
+			t.Fatalf("got name %s, wanted %s", s.Name, "hello")
+		}
+		n++
+	}
+
+	if n != 3 {
+		t.Fatalf("got %d uploads, wanted %d", n, 3)
+	}
+
+	for k, v := range map[string]string{"sortBy": "Name", "sortOrder": "Asc", "name": "hello"} {
+		if len(query[k]) != 1 || query[k][0] != v {
+			t.Fatalf("got query param %s=%v, wanted %s", k, query[k], v)
+		}
+	}
+}
+
+func TestListResumeFromCursor(t *testing.T) {
+	routes := routeMap{
+		"/user/uploads": {
+			http.MethodGet: uploadsHandler,
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	it, err := client.List(context.Background())
+	if err != nil {
+		t.Fatalf("failed to create iterator: %v", err)
+	}
+
+	if it.Cursor() != "" {
+		t.Fatalf("expected empty cursor before iteration")
+	}
+
+	_, err = it.Next(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get next upload: %v", err)
+	}
+
+	it, err = client.List(context.Background(), WithCursor(it.Cursor()))
+	if err != nil {
+		t.Fatalf("failed to create iterator: %v", err)
+	}
+
+	s, err := it.Next(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get next upload: %v", err)
+	}
+
+	if s.Cid.String() != thanksRoot {
+		t.Fatalf("got cid %s, wanted %s", s.Cid.String(), thanksRoot)
+	}
+}
+
+func TestListAll(t *testing.T) {
+	routes := routeMap{
+		"/user/uploads": {
+			http.MethodGet: uploadsHandler,
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	it, err := client.List(context.Background())
+	if err != nil {
+		t.Fatalf("failed to create iterator: %v", err)
+	}
+	defer it.Close()
+
+	var n int
+	for range it.All(context.Background()) {
+		n++
+	}
+
+	if it.Err() != nil {
+		t.Fatalf("failed to iterate: %v", it.Err())
+	}
+
+	if n != 3 {
+		t.Fatalf("got %d uploads, wanted %d", n, 3)
+	}
+}
+
+func TestListNextPage(t *testing.T) {
+	routes := routeMap{
+		"/user/uploads": {
+			http.MethodGet: uploadsHandler,
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	it, err := client.List(context.Background(), WithMaxResults(2))
+	if err != nil {
+		t.Fatalf("failed to create iterator: %v", err)
+	}
+	defer it.Close()
+
+	page, err := it.NextPage(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get next page: %v", err)
+	}
+
+	if len(page) != 2 {
+		t.Fatalf("got %d uploads, wanted %d", len(page), 2)
+	}
+
+	_, err = it.NextPage(context.Background())
+	if err != io.EOF {
+		t.Fatalf("got error %v, wanted %v", err, io.EOF)
+	}
+}
+
+func TestListAllCanceledDuringFetch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	requested := make(chan struct{}, 2)
+	routes := routeMap{
+		"/user/uploads": {
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) {
+				requested <- struct{}{}
+				<-r.Context().Done()
+			},
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	it, err := client.List(context.Background())
+	if err != nil {
+		t.Fatalf("failed to create iterator: %v", err)
+	}
+	defer it.Close()
+
+	ch := it.All(ctx)
+	<-requested
+	cancel()
+	for range ch {
+		t.Fatalf("received an upload from a canceled listing")
+	}
+	if it.Err() != context.Canceled {
+		t.Fatalf("got error %v, wanted %v", it.Err(), context.Canceled)
+	}
+}
+
+func TestListNextCanceled(t *testing.T) {
+	requested := make(chan struct{}, 1)
+	var requests int32
+	routes := routeMap{
+		"/user/uploads": {
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&requests, 1) == 1 {
+					requested <- struct{}{}
+					<-r.Context().Done()
+					return
+				}
+				uploadsHandler(w, r)
+			},
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	it, err := client.List(context.Background())
+	if err != nil {
+		t.Fatalf("failed to create iterator: %v", err)
+	}
+	defer it.Close()
+
+	// The request is made with the context passed to Next, not List.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-requested
+		cancel()
+	}()
+	if _, err := it.Next(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, wanted %v", err, context.Canceled)
+	}
+
+	s, err := it.Next(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get next upload after cancellation: %v", err)
+	}
+	if s.Cid.String() != helloRoot {
+		t.Fatalf("got cid %s, wanted %s", s.Cid.String(), helloRoot)
+	}
+}
+
+func TestListReleasesContextAtEnd(t *testing.T) {
+	routes := routeMap{
+		"/user/uploads": {
+			http.MethodGet: uploadsHandler,
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	it, err := client.List(context.Background())
+	if err != nil {
+		t.Fatalf("failed to create iterator: %v", err)
+	}
+	for {
+		if _, err := it.Next(context.Background()); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("failed to iterate: %v", err)
+		}
+	}
+	// Close is not needed once iteration has reached the end.
+	if it.ctx.Err() == nil {
+		t.Fatalf("iterator context was not released at the end of iteration")
+	}
+}
@@ -0,0 +1,72 @@
+// Package logging defines the logger interface used by the web3.storage client
+// and its subpackages.
+package logging
+
+import (
+	"fmt"
+	"log"
+)
+
+// Logger is a leveled, printf style logger. It is satisfied by the loggers in
+// github.com/ipfs/go-log and go.uber.org/zap (SugaredLogger) among others.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// Nop is a Logger that discards all messages.
+var Nop Logger = nopLogger{}
+
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Infof(string, ...interface{})  {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+func (nopLogger) Errorf(string, ...interface{}) {}
+
+// Level is the minimum level of messages written by a Logger created with
+// FromStd.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// FromStd creates a Logger that writes messages at or above the passed level
+// to a logger from the standard library.
+func FromStd(l *log.Logger, level Level) Logger {
+	return &stdLogger{l, level}
+}
+
+type stdLogger struct {
+	l     *log.Logger
+	level Level
+}
+
+func (s *stdLogger) logf(level Level, prefix, format string, args []interface{}) {
+	if level < s.level {
+		return
+	}
+	s.l.Output(3, prefix+fmt.Sprintf(format, args...))
+}
+
+func (s *stdLogger) Debugf(format string, args ...interface{}) {
+	s.logf(LevelDebug, "DEBUG ", format, args)
+}
+
+func (s *stdLogger) Infof(format string, args ...interface{}) {
+	s.logf(LevelInfo, "INFO ", format, args)
+}
+
+func (s *stdLogger) Warnf(format string, args ...interface{}) {
+	s.logf(LevelWarn, "WARN ", format, args)
+}
+
+func (s *stdLogger) Errorf(format string, args ...interface{}) {
+	s.logf(LevelError, "ERROR ", format, args)
+}
@@ -0,0 +1,139 @@
+package w3s
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/web3-storage/go-w3s-client/logging"
+)
+
+// Middleware wraps a http.RoundTripper to inspect or modify requests made to
+// the API and the responses received.
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is an adapter to allow the use of ordinary functions as a
+// http.RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func chainMiddleware(rt http.RoundTripper, mws []Middleware) http.RoundTripper {
+	for i := len(mws) - 1; i >= 0; i-- {
+		rt = mws[i](rt)
+	}
+	return rt
+}
+
+// LogRequests creates middleware that logs the method, URL, response status and
+// duration of each request at info level, or the error at error level if the
+// request fails. Use logging.FromStd to log with a logger from the standard
+// library.
+func LogRequests(log logging.Logger) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			res, err := next.RoundTrip(req)
+			if err != nil {
+				log.Errorf("%s %s failed after %v: %v", req.Method, req.URL, time.Since(start), err)
+				return nil, err
+			}
+			log.Infof("%s %s %d %v", req.Method, req.URL, res.StatusCode, time.Since(start))
+			return res, nil
+		})
+	}
+}
+
+// AddRequestID creates middleware that adds a random ID to each request in the
+// X-Request-Id header, unless it is already set.
+func AddRequestID() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("X-Request-Id") != "" {
+				return next.RoundTrip(req)
+			}
+			b := make([]byte, 16)
+			_, err := rand.Read(b)
+			if err != nil {
+				return nil, fmt.Errorf("generating request ID: %w", err)
+			}
+			req = req.Clone(req.Context())
+			req.Header.Set("X-Request-Id", hex.EncodeToString(b))
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// AddHeaders creates middleware that sets the passed headers on each request.
+func AddHeaders(h http.Header) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			for k, v := range h {
+				req.Header[k] = append([]string(nil), v...)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// RefreshAuth creates middleware that obtains a new auth token by calling the
+// passed function when the API responds with 401 Unauthorized. The request is
+// then retried with the new token, if the request body can be replayed. The
+// new token is used for all subsequent requests.
+func RefreshAuth(refresh func(context.Context) (string, error)) Middleware {
+	var mu sync.Mutex
+	var token string
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			current := token
+			mu.Unlock()
+
+			if current != "" {
+				req = req.Clone(req.Context())
+				req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", current))
+			}
+
+			res, err := next.RoundTrip(req)
+			if err != nil || res.StatusCode != http.StatusUnauthorized {
+				return res, err
+			}
+
+			mu.Lock()
+			// Another request may have refreshed the token already.
+			if token == current {
+				t, err := refresh(req.Context())
+				if err != nil {
+					mu.Unlock()
+					res.Body.Close()
+					return nil, fmt.Errorf("refreshing auth token: %w", err)
+				}
+				token = t
+			}
+			current = token
+			mu.Unlock()
+
+			retry := req.Clone(req.Context())
+			if req.Body != nil && req.Body != http.NoBody {
+				if req.GetBody == nil {
+					// Cannot replay the body, return the 401 response.
+					return res, nil
+				}
+				retry.Body, err = req.GetBody()
+				if err != nil {
+					return res, nil
+				}
+			}
+			res.Body.Close()
+			retry.Header.Set("Authorization", fmt.Sprintf("Bearer %s", current))
+			return next.RoundTrip(retry)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package w3s
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+)
+
+func TestMiddleware(t *testing.T) {
+	var hdrs http.Header
+	routes := routeMap{
+		"/status/" + helloRoot: {
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) {
+				hdrs = r.Header
+				statusHelloCarHandler(w, r)
+			},
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	var log recordingLogger
+	client, err := NewClient(
+		WithHTTPClient(hc),
+		WithToken(validToken),
+		WithMiddleware(
+			LogRequests(&log),
+			AddRequestID(),
+			AddHeaders(http.Header{"X-Custom": []string{"value"}}),
+		),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	c, _ := cid.Parse(helloRoot)
+	_, err = client.Status(context.Background(), c)
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+
+	if hdrs.Get("X-Request-Id") == "" {
+		t.Fatalf("missing request ID header")
+	}
+	if hdrs.Get("X-Custom") != "value" {
+		t.Fatalf("got custom header %q, wanted %q", hdrs.Get("X-Custom"), "value")
+	}
+	if hdrs.Get("X-Client") != clientName {
+		t.Fatalf("got client header %q, wanted %q", hdrs.Get("X-Client"), clientName)
+	}
+	if len(log.lines) != 1 || !strings.HasPrefix(log.lines[0], "INFO GET ") {
+		t.Fatalf("got log lines %q, wanted one INFO line for the request", log.lines)
+	}
+	if _, ok := hc.Transport.(urlRewriteTransport); !ok {
+		t.Fatalf("passed HTTP client was modified")
+	}
+}
+
+func TestRefreshAuthMiddleware(t *testing.T) {
+	routes := routeMap{
+		"/status/" + helloRoot: {
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer refreshed" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				statusHelloCarHandler(w, r)
+			},
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	var refreshes int
+	client, err := NewClient(
+		WithHTTPClient(hc),
+		WithToken("expired"),
+		WithMiddleware(RefreshAuth(func(context.Context) (string, error) {
+			refreshes++
+			return "refreshed", nil
+		})),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	c, _ := cid.Parse(helloRoot)
+	for i := 0; i < 2; i++ {
+		_, err = client.Status(context.Background(), c)
+		if err != nil {
+			t.Fatalf("failed to send request: %v", err)
+		}
+	}
+
+	if refreshes != 1 {
+		t.Fatalf("got %d refreshes, wanted %d", refreshes, 1)
+	}
+}
@@ -0,0 +1,118 @@
+package name
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const clientName = "web3.storage/go"
+
+// Client is a HTTP API client to the web3.storage name service.
+type Client interface {
+	Publish(context.Context, *Revision, *WritableName) error
+	Resolve(context.Context, *Name) (*Revision, error)
+}
+
+type clientConfig struct {
+	token    string
+	endpoint string
+	hc       *http.Client
+}
+
+type client struct {
+	cfg *clientConfig
+}
+
+// NewClient creates a new web3.storage name service client.
+func NewClient(options ...Option) (Client, error) {
+	cfg := clientConfig{
+		endpoint: "https://api.web3.storage",
+		hc:       &http.Client{},
+	}
+	for _, opt := range options {
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+	return &client{cfg: &cfg}, nil
+}
+
+// Publish signs the revision using the private key for the name and publishes
+// it so that resolving the name returns the revision value.
+func (c *client) Publish(ctx context.Context, rev *Revision, key *WritableName) error {
+	record, err := rev.Record(key)
+	if err != nil {
+		return err
+	}
+
+	body := bytes.NewBufferString(base64.StdEncoding.EncodeToString(record))
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/name/%s", c.cfg.endpoint, rev.Name), body)
+	if err != nil {
+		return err
+	}
+	c.addHeaders(req)
+	res, err := c.cfg.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 && res.StatusCode != 202 {
+		return fmt.Errorf("unexpected response status: %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Resolve retrieves the current revision for the name. The signature and
+// validity of the record returned by the service is verified.
+func (c *client) Resolve(ctx context.Context, name *Name) (*Revision, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/name/%s", c.cfg.endpoint, name), nil)
+	if err != nil {
+		return nil, err
+	}
+	c.addHeaders(req)
+	res, err := c.cfg.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected response status: %d", res.StatusCode)
+	}
+
+	var out struct {
+		Value  string `json:"value"`
+		Record string `json:"record"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&out)
+	if err != nil {
+		return nil, fmt.Errorf("decode resolve response: %w", err)
+	}
+
+	record, err := base64.StdEncoding.DecodeString(out.Record)
+	if err != nil {
+		return nil, fmt.Errorf("decoding record: %w", err)
+	}
+	rev, err := FromRecord(name, record)
+	if err != nil {
+		return nil, err
+	}
+	if rev.Value != out.Value {
+		return nil, fmt.Errorf("record value %q does not match resolved value %q", rev.Value, out.Value)
+	}
+	return rev, nil
+}
+
+func (c *client) addHeaders(req *http.Request) {
+	if c.cfg.token != "" {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.cfg.token))
+	}
+	req.Header.Add("X-Client", clientName)
+}
+
+var _ Client = (*client)(nil)
@@ -0,0 +1,123 @@
+// Package name implements mutable IPNS names published and resolved using the
+// web3.storage name service (w3name).
+package name
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/multiformats/go-multibase"
+)
+
+// Name is an IPNS name, which can be resolved to obtain the value it currently
+// points to.
+type Name struct {
+	id peer.ID
+}
+
+// Parse parses the string representation of a name e.g. "k51qzi5uqu5d...".
+func Parse(s string) (*Name, error) {
+	c, err := cid.Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("decoding name: %w", err)
+	}
+	id, err := peer.FromCid(c)
+	if err != nil {
+		return nil, fmt.Errorf("decoding name: %w", err)
+	}
+	return &Name{id}, nil
+}
+
+// ID returns the peer ID derived from the public key of the name.
+func (n *Name) ID() peer.ID {
+	return n.id
+}
+
+// String returns the name encoded as a base36 libp2p-key CID.
+func (n *Name) String() string {
+	s, err := peer.ToCid(n.id).StringOfBase(multibase.Base36)
+	if err != nil {
+		// Base36 is always available.
+		panic(err)
+	}
+	return s
+}
+
+// WritableName is a Name that also holds the private key needed to publish
+// new revisions.
+type WritableName struct {
+	Name
+	key crypto.PrivKey
+}
+
+// Create creates a new name with a new Ed25519 key pair.
+func Create() (*WritableName, error) {
+	key, _, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		return nil, fmt.Errorf("generating key: %w", err)
+	}
+	return FromKey(key)
+}
+
+// FromKey creates a name from an existing private key.
+func FromKey(key crypto.PrivKey) (*WritableName, error) {
+	id, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &WritableName{Name{id}, key}, nil
+}
+
+// FromBytes creates a name from a private key serialized using Bytes.
+func FromBytes(b []byte) (*WritableName, error) {
+	key, err := crypto.UnmarshalPrivateKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("decoding key: %w", err)
+	}
+	return FromKey(key)
+}
+
+// Load reads a name from the private key stored in the file at path.
+func Load(path string) (*WritableName, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return FromBytes(b)
+}
+
+// Key returns the private key for the name.
+func (n *WritableName) Key() crypto.PrivKey {
+	return n.key
+}
+
+// Bytes serializes the private key for the name.
+func (n *WritableName) Bytes() ([]byte, error) {
+	return crypto.MarshalPrivateKey(n.key)
+}
+
+// Save writes the private key for the name to the file at path. The file is
+// only readable by the current user. An existing file is NOT overwritten.
+func (n *WritableName) Save(path string) error {
+	b, err := n.Bytes()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("key file already exists: %s", path)
+		}
+		return err
+	}
+	_, err = f.Write(b)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,137 @@
+package name
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveLoad(t *testing.T) {
+	n, err := Create()
+	if err != nil {
+		t.Fatalf("failed to create name: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "name.key")
+	err = n.Save(path)
+	if err != nil {
+		t.Fatalf("failed to save name: %v", err)
+	}
+
+	err = n.Save(path)
+	if err == nil {
+		t.Fatalf("expected error overwriting key file")
+	}
+
+	l, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to load name: %v", err)
+	}
+
+	if l.String() != n.String() {
+		t.Fatalf("got name %s, wanted %s", l.String(), n.String())
+	}
+
+	p, err := Parse(n.String())
+	if err != nil {
+		t.Fatalf("failed to parse name: %v", err)
+	}
+
+	if p.ID() != n.ID() {
+		t.Fatalf("got ID %s, wanted %s", p.ID(), n.ID())
+	}
+}
+
+func TestRecordVerification(t *testing.T) {
+	n, _ := Create()
+	other, _ := Create()
+
+	rev := NewRevision(&n.Name, "/ipfs/bafybeicymili4gmgoa4xpx5jfghi7leffvai4fd47f6nxgrhq4ug6ekiga")
+	_, err := rev.Record(other)
+	if err == nil {
+		t.Fatalf("expected error signing with the wrong key")
+	}
+
+	rev = rev.Increment("/ipfs/bafybeid7orcaehmy2lzlkr4wnfgexmm2xoonmamaimdsjycex7wu4pjip4")
+	record, err := rev.Record(n)
+	if err != nil {
+		t.Fatalf("failed to create record: %v", err)
+	}
+
+	_, err = FromRecord(&other.Name, record)
+	if err == nil {
+		t.Fatalf("expected error verifying record for another name")
+	}
+
+	vrev, err := FromRecord(&n.Name, record)
+	if err != nil {
+		t.Fatalf("failed to verify record: %v", err)
+	}
+
+	if vrev.Value != rev.Value || vrev.Sequence != 1 {
+		t.Fatalf("got revision %s@%d, wanted %s@%d", vrev.Value, vrev.Sequence, rev.Value, 1)
+	}
+
+	expired := NewRevision(&n.Name, rev.Value)
+	expired.Validity = time.Now().Add(-time.Minute)
+	record, _ = expired.Record(n)
+	_, err = FromRecord(&n.Name, record)
+	if err == nil {
+		t.Fatalf("expected error verifying expired record")
+	}
+}
+
+func TestPublishResolve(t *testing.T) {
+	records := map[string]string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/name/")
+		switch r.Method {
+		case http.MethodPost:
+			if r.Header.Get("Authorization") != "Bearer validtoken" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			b, _ := io.ReadAll(r.Body)
+			records[key] = string(b)
+			w.WriteHeader(http.StatusAccepted)
+		case http.MethodGet:
+			record, ok := records[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{
+				"value":  "/ipfs/bafybeicymili4gmgoa4xpx5jfghi7leffvai4fd47f6nxgrhq4ug6ekiga",
+				"record": record,
+			})
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(WithEndpoint(ts.URL), WithToken("validtoken"))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	n, _ := Create()
+	rev := NewRevision(&n.Name, "/ipfs/bafybeicymili4gmgoa4xpx5jfghi7leffvai4fd47f6nxgrhq4ug6ekiga")
+	err = client.Publish(context.Background(), rev, n)
+	if err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+
+	rrev, err := client.Resolve(context.Background(), &n.Name)
+	if err != nil {
+		t.Fatalf("failed to resolve: %v", err)
+	}
+
+	if rrev.Value != rev.Value {
+		t.Fatalf("got value %s, wanted %s", rrev.Value, rev.Value)
+	}
+}
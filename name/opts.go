@@ -0,0 +1,40 @@
+package name
+
+import "net/http"
+
+// Option is an option configuring a name service client. The options mirror
+// those of the web3.storage API client so the same configuration can be used
+// for both.
+type Option func(cfg *clientConfig) error
+
+// WithEndpoint sets the URL of the root API when making requests (default
+// https://api.web3.storage).
+func WithEndpoint(endpoint string) Option {
+	return func(cfg *clientConfig) error {
+		if endpoint != "" {
+			cfg.endpoint = endpoint
+		}
+		return nil
+	}
+}
+
+// WithToken sets the auth token to use in the Authorization header when making
+// requests to the API. Resolving names does not require a token.
+func WithToken(token string) Option {
+	return func(cfg *clientConfig) error {
+		cfg.token = token
+		return nil
+	}
+}
+
+// WithHTTPClient sets the HTTP client to use when making requests which allows
+// timeouts and redirect behaviour to be configured. The default is to use the
+// DefaultClient from the Go standard library.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(cfg *clientConfig) error {
+		if hc != nil {
+			cfg.hc = hc
+		}
+		return nil
+	}
+}
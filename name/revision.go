@@ -0,0 +1,94 @@
+package name
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ipfs/go-ipns"
+	pb "github.com/ipfs/go-ipns/pb"
+)
+
+// DefaultValidity is the duration a revision is valid for if not specified.
+const DefaultValidity = time.Hour * 24 * 365
+
+// DefaultTTL is the duration a resolved revision may be cached for if not
+// specified.
+const DefaultTTL = time.Minute * 5
+
+// Revision is a value a name points to at a given sequence number.
+type Revision struct {
+	Name     *Name
+	Value    string
+	Sequence uint64
+	// Validity is the time after which the revision is no longer valid.
+	Validity time.Time
+	// TTL is the duration the revision may be cached for after resolution.
+	TTL time.Duration
+}
+
+// NewRevision creates the initial revision (sequence number 0) for a name.
+func NewRevision(name *Name, value string) *Revision {
+	return &Revision{
+		Name:     name,
+		Value:    value,
+		Validity: time.Now().Add(DefaultValidity),
+		TTL:      DefaultTTL,
+	}
+}
+
+// Increment creates the next revision for the name, pointing to a new value.
+func (r *Revision) Increment(value string) *Revision {
+	return &Revision{
+		Name:     r.Name,
+		Value:    value,
+		Sequence: r.Sequence + 1,
+		Validity: time.Now().Add(DefaultValidity),
+		TTL:      r.TTL,
+	}
+}
+
+// Record creates a signed IPNS record for the revision.
+func (r *Revision) Record(key *WritableName) ([]byte, error) {
+	if key.ID() != r.Name.ID() {
+		return nil, fmt.Errorf("key does not match name %s", r.Name)
+	}
+	entry, err := ipns.Create(key.Key(), []byte(r.Value), r.Sequence, r.Validity, r.TTL)
+	if err != nil {
+		return nil, fmt.Errorf("creating record: %w", err)
+	}
+	err = ipns.EmbedPublicKey(key.Key().GetPublic(), entry)
+	if err != nil {
+		return nil, fmt.Errorf("embedding public key: %w", err)
+	}
+	return proto.Marshal(entry)
+}
+
+// FromRecord verifies a serialized IPNS record was signed by the key for the
+// passed name and has not expired, and returns the revision it describes.
+func FromRecord(name *Name, record []byte) (*Revision, error) {
+	var entry pb.IpnsEntry
+	err := proto.Unmarshal(record, &entry)
+	if err != nil {
+		return nil, fmt.Errorf("decoding record: %w", err)
+	}
+	pk, err := ipns.ExtractPublicKey(name.ID(), &entry)
+	if err != nil {
+		return nil, fmt.Errorf("extracting public key: %w", err)
+	}
+	err = ipns.Validate(pk, &entry)
+	if err != nil {
+		return nil, fmt.Errorf("validating record: %w", err)
+	}
+	validity, err := ipns.GetEOL(&entry)
+	if err != nil {
+		return nil, err
+	}
+	return &Revision{
+		Name:     name,
+		Value:    string(entry.GetValue()),
+		Sequence: entry.GetSequence(),
+		Validity: validity,
+		TTL:      time.Duration(entry.GetTtl()),
+	}, nil
+}
@@ -4,10 +4,16 @@ import (
 	"fmt"
 	"io/fs"
 	"net/http"
+	"net/url"
 	"time"
 
+	"github.com/ipfs/go-cid"
 	ds "github.com/ipfs/go-datastore"
 	"github.com/multiformats/go-multiaddr"
+	"github.com/web3-storage/go-w3s-client/logging"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 )
 
 // Option is an option configuring a web3.storage client.
@@ -34,8 +40,9 @@ func WithToken(token string) Option {
 }
 
 // WithDatastore sets the underlying datastore to use when reading or writing
-// DAG block data. The default is to use a new in-memory store per Get/Put
-// request.
+// DAG block data. Blocks are kept in it after each request, so it acts as a
+// cache that can be inspected using the Blockstore method of the client. The
+// default is to use a new in-memory store per Get/Put request.
 func WithDatastore(ds ds.Batching) Option {
 	return func(cfg *clientConfig) error {
 		if ds != nil {
@@ -45,6 +52,78 @@ func WithDatastore(ds ds.Batching) Option {
 	}
 }
 
+// WithDatastorePath sets the path of an on-disk (LevelDB) datastore to use when
+// reading or writing DAG block data. It is opened when the client is created
+// and closed by Client.Close, and is ignored if WithDatastore is also passed.
+func WithDatastorePath(path string) Option {
+	return func(cfg *clientConfig) error {
+		cfg.dsPath = path
+		return nil
+	}
+}
+
+// WithUploadCleanup causes Put and PutCar to remove the blocks they imported
+// from the configured datastore once they have been uploaded. Blocks that were already
+// held, for example from an earlier Get, are kept. It has no effect when no
+// datastore is configured.
+func WithUploadCleanup() Option {
+	return func(cfg *clientConfig) error {
+		cfg.cleanup = true
+		return nil
+	}
+}
+
+// WithCacheFirst causes Get to serve content from the configured datastore
+// when it holds the whole DAG, without making a request. If only parts of the
+// DAG are held, just the missing subtrees are downloaded. It has no effect when
+// no datastore is configured.
+func WithCacheFirst() Option {
+	return func(cfg *clientConfig) error {
+		cfg.cacheFirst = true
+		return nil
+	}
+}
+
+// WithGateways sets the base URLs of trustless IPFS HTTP gateways, such as
+// https://dweb.link, that Get falls back to when the API fails or returns an
+// error status. Content is requested from a gateway as a CAR, and is only used
+// if every block matches its CID, the DAG is complete and the CAR holds no
+// other blocks.
+func WithGateways(urls ...string) Option {
+	return func(cfg *clientConfig) error {
+		for _, u := range urls {
+			pu, err := url.Parse(u)
+			if err != nil {
+				return fmt.Errorf("parsing gateway URL: %w", err)
+			}
+			if pu.Scheme != "http" && pu.Scheme != "https" {
+				return fmt.Errorf("invalid gateway URL: %s", u)
+			}
+		}
+		cfg.gateways = append(cfg.gateways, urls...)
+		return nil
+	}
+}
+
+// WithGatewayStrategy sets how gateways are tried, one of GatewaySequential
+// (the default) or GatewayRace.
+func WithGatewayStrategy(s GatewayStrategy) Option {
+	return func(cfg *clientConfig) error {
+		cfg.gatewayStrategy = s
+		return nil
+	}
+}
+
+// WithGatewayMaxSize sets the maximum size in bytes of a CAR read from a
+// gateway, which is held in memory until it has been verified. The default is
+// 1 GiB.
+func WithGatewayMaxSize(size int64) Option {
+	return func(cfg *clientConfig) error {
+		cfg.gatewayMaxSize = size
+		return nil
+	}
+}
+
 // WithHTTPClient sets the HTTP client to use when making requests which allows
 // timeouts and redirect behaviour to be configured. The default is to use the
 // DefaultClient from the Go standard library.
@@ -57,6 +136,168 @@ func WithHTTPClient(hc *http.Client) Option {
 	}
 }
 
+// WithMiddleware adds middleware that wraps the transport of the HTTP client,
+// allowing requests and responses to be inspected or modified. Middleware is
+// applied in the order passed i.e. the first is the outermost.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(cfg *clientConfig) error {
+		cfg.middlewares = append(cfg.middlewares, mw...)
+		return nil
+	}
+}
+
+// WithTracerProvider sets the OpenTelemetry tracer provider used to create
+// spans for API operations. The default is the global tracer provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(cfg *clientConfig) error {
+		if tp != nil {
+			cfg.tp = tp
+		}
+		return nil
+	}
+}
+
+// WithMeterProvider sets the OpenTelemetry meter provider used to record upload
+// metrics. The default is the global meter provider.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(cfg *clientConfig) error {
+		if mp != nil {
+			cfg.mp = mp
+		}
+		return nil
+	}
+}
+
+// WithLogger sets the logger used to report progress and failures. The default
+// is to discard log messages.
+func WithLogger(log logging.Logger) Option {
+	return func(cfg *clientConfig) error {
+		if log != nil {
+			cfg.log = log
+		}
+		return nil
+	}
+}
+
+// WithTimeout sets the time limit for each API request, including any retries
+// and reading the response body. The default is no time limit.
+func WithTimeout(timeout time.Duration) Option {
+	return func(cfg *clientConfig) error {
+		if timeout < 0 {
+			return fmt.Errorf("timeout must not be negative")
+		}
+		cfg.timeout = timeout
+		return nil
+	}
+}
+
+// WithMaxRetries sets the number of times a request is retried if it fails
+// with a network error or a 429, 500, 502, 503 or 504 response. The default is
+// not to retry. Only requests that are safe to send again are retried: those
+// with a GET, HEAD, OPTIONS, TRACE, PUT or DELETE method and uploads to
+// POST /car, which are content addressed. See WithRetryEndpoint to retry other
+// requests.
+func WithMaxRetries(n int) Option {
+	return func(cfg *clientConfig) error {
+		if n < 0 {
+			return fmt.Errorf("max retries must not be negative")
+		}
+		cfg.retry.max = n
+		return nil
+	}
+}
+
+// WithRetryBackoff sets the delay before the first retry of a request, which
+// doubles for each subsequent retry up to max. A delay requested by the API in
+// a Retry-After header is used instead, also capped at max. The default is 1s
+// doubling up to 30s.
+func WithRetryBackoff(min, max time.Duration) Option {
+	return func(cfg *clientConfig) error {
+		if min <= 0 || max < min {
+			return fmt.Errorf("invalid retry backoff: %v to %v", min, max)
+		}
+		cfg.retry.minBackoff = min
+		cfg.retry.maxBackoff = max
+		return nil
+	}
+}
+
+// WithRetryEndpoint allows requests matching pattern to be retried although
+// their method is not idempotent. A request such as POST /pins may have been
+// acted on before failing, so sending it again could repeat its effect. See
+// WithEndpointRateLimit for the pattern syntax.
+func WithRetryEndpoint(pattern string) Option {
+	return func(cfg *clientConfig) error {
+		cfg.retry.endpoints = append(cfg.retry.endpoints, pattern)
+		return nil
+	}
+}
+
+// WithRateLimit limits the rate of requests made to the API to r requests per
+// second, allowing bursts of up to burst requests. The limit is shared by all
+// methods of the client.
+func WithRateLimit(r rate.Limit, burst int) Option {
+	return func(cfg *clientConfig) error {
+		if r <= 0 {
+			return fmt.Errorf("rate limit must be greater than zero")
+		}
+		cfg.limits.rate = r
+		cfg.limits.burst = burst
+		return nil
+	}
+}
+
+// WithMaxConcurrentRequests limits the number of requests to the API that may be
+// in flight at once. A request is in flight until its response body is closed.
+// The limit is shared by all methods of the client.
+func WithMaxConcurrentRequests(n int) Option {
+	return func(cfg *clientConfig) error {
+		if n < 1 {
+			return fmt.Errorf("max concurrent requests must be at least 1")
+		}
+		cfg.limits.concurrency = n
+		return nil
+	}
+}
+
+// WithEndpointRateLimit overrides the rate limit for requests matching pattern.
+// A pattern is a path prefix optionally preceded by a method and a space e.g.
+// "POST /car" or "/status/". When several patterns match a request the one with
+// the longest path prefix is used.
+func WithEndpointRateLimit(pattern string, r rate.Limit, burst int) Option {
+	return func(cfg *clientConfig) error {
+		if r <= 0 {
+			return fmt.Errorf("rate limit must be greater than zero")
+		}
+		l := cfg.endpointLimits(pattern)
+		l.rate = r
+		l.burst = burst
+		cfg.endpoints[pattern] = l
+		return nil
+	}
+}
+
+// WithEndpointMaxConcurrentRequests overrides the maximum number of in flight
+// requests matching pattern. See WithEndpointRateLimit for the pattern syntax.
+func WithEndpointMaxConcurrentRequests(pattern string, n int) Option {
+	return func(cfg *clientConfig) error {
+		if n < 1 {
+			return fmt.Errorf("max concurrent requests must be at least 1")
+		}
+		l := cfg.endpointLimits(pattern)
+		l.concurrency = n
+		cfg.endpoints[pattern] = l
+		return nil
+	}
+}
+
+func (cfg *clientConfig) endpointLimits(pattern string) limitConfig {
+	if cfg.endpoints == nil {
+		cfg.endpoints = map[string]limitConfig{}
+	}
+	return cfg.endpoints[pattern]
+}
+
 // PutOption is an option configuring a call to Put.
 type PutOption func(cfg *putConfig) error
 
@@ -81,6 +322,60 @@ func WithDirname(dirname string) PutOption {
 	}
 }
 
+// WithName sets a human readable name for the upload, which is displayed in
+// the list of uploads.
+func WithName(name string) PutOption {
+	return func(cfg *putConfig) error {
+		cfg.name = name
+		return nil
+	}
+}
+
+// WithQuotaCheck causes Put to check the size of the DAG against the storage
+// remaining for the account before uploading, failing with ErrQuotaExceeded
+// if it would not fit. This requires additional requests to the API.
+func WithQuotaCheck() PutOption {
+	return func(cfg *putConfig) error {
+		cfg.quota = true
+		return nil
+	}
+}
+
+// WithFiles adds files or directories to upload after the one passed to Put,
+// each as a separate root that keeps its own CID, unlike uploading a directory
+// of them. They are uploaded together in one CAR with every root in its header
+// and Put returns their CIDs after that of the file passed to it.
+func WithFiles(files ...fs.File) PutOption {
+	return func(cfg *putConfig) error {
+		cfg.files = append(cfg.files, files...)
+		return nil
+	}
+}
+
+// SyncOption is an option configuring a call to Sync.
+type SyncOption func(cfg *syncConfig) error
+
+// WithSyncName sets a human readable name for the uploads made by Sync.
+func WithSyncName(name string) SyncOption {
+	return func(cfg *syncConfig) error {
+		cfg.name = name
+		return nil
+	}
+}
+
+// WithSyncKey sets the key the sync index is stored under. The default is the
+// absolute path of the directory, so a directory that is moved or synced from
+// another mount point must pass the same key to reuse its index.
+func WithSyncKey(key string) SyncOption {
+	return func(cfg *syncConfig) error {
+		if key == "" {
+			return fmt.Errorf("sync key must not be empty")
+		}
+		cfg.key = key
+		return nil
+	}
+}
+
 // ListOption is an option configuring a call to List.
 type ListOption func(cfg *listConfig) error
 
@@ -92,6 +387,66 @@ func WithBefore(before time.Time) ListOption {
 	}
 }
 
+// WithAfter sets the time that items in the list were uploaded after.
+func WithAfter(after time.Time) ListOption {
+	return func(cfg *listConfig) error {
+		cfg.after = after
+		return nil
+	}
+}
+
+// WithSortBy sets the field the list is sorted by, one of SortByDate (the
+// default) or SortByName.
+func WithSortBy(sortBy string) ListOption {
+	return func(cfg *listConfig) error {
+		switch sortBy {
+		case SortByDate:
+			cfg.sortBy = "Date"
+		case SortByName:
+			cfg.sortBy = "Name"
+		default:
+			return fmt.Errorf("invalid sort field: %s", sortBy)
+		}
+		return nil
+	}
+}
+
+// WithSortOrder sets the direction the list is sorted in, one of
+// SortOrderDesc (the default) or SortOrderAsc.
+func WithSortOrder(sortOrder string) ListOption {
+	return func(cfg *listConfig) error {
+		switch sortOrder {
+		case SortOrderAsc:
+			cfg.sortOrder = "Asc"
+		case SortOrderDesc:
+			cfg.sortOrder = "Desc"
+		default:
+			return fmt.Errorf("invalid sort order: %s", sortOrder)
+		}
+		return nil
+	}
+}
+
+// WithNameFilter restricts the list to uploads whose name matches the passed
+// string.
+func WithNameFilter(name string) ListOption {
+	return func(cfg *listConfig) error {
+		cfg.name = name
+		return nil
+	}
+}
+
+// WithCursor resumes listing from a cursor obtained from UploadIterator.Cursor.
+// The first item returned will be the one following the last item returned
+// when the cursor was taken. Filtering and sorting options are encoded in the
+// cursor so other options (except WithMaxResults) are ignored.
+func WithCursor(cursor string) ListOption {
+	return func(cfg *listConfig) error {
+		cfg.cursor = cursor
+		return nil
+	}
+}
+
 // WithMaxResults sets the maximum number of results that will be available from
 // the iterator.
 func WithMaxResults(maxResults int) ListOption {
@@ -134,3 +489,140 @@ func WithPinMeta(key, value string) PinOption {
 		return nil
 	}
 }
+
+// PinWaitOption is an option configuring a call to PinAndWait. Any PinOption
+// may also be passed to PinAndWait.
+type PinWaitOption interface {
+	applyPinWait(cfg *pinWaitConfig) error
+}
+
+func (o PinOption) applyPinWait(cfg *pinWaitConfig) error {
+	cfg.pin = append(cfg.pin, o)
+	return nil
+}
+
+type pinWaitOption func(cfg *pinWaitConfig) error
+
+func (o pinWaitOption) applyPinWait(cfg *pinWaitConfig) error {
+	return o(cfg)
+}
+
+// WithPinWaitTimeout sets the maximum time PinAndWait waits for the data to be
+// pinned. The default is to wait until the context is canceled.
+func WithPinWaitTimeout(timeout time.Duration) PinWaitOption {
+	return pinWaitOption(func(cfg *pinWaitConfig) error {
+		cfg.timeout = timeout
+		return nil
+	})
+}
+
+// WithPinPollInterval sets the initial and maximum interval between status
+// checks made by PinAndWait (default 1s and 30s).
+func WithPinPollInterval(initial, max time.Duration) PinWaitOption {
+	return pinWaitOption(func(cfg *pinWaitConfig) error {
+		if initial <= 0 || max < initial {
+			return fmt.Errorf("invalid poll interval: %v-%v", initial, max)
+		}
+		cfg.pollInterval = initial
+		cfg.maxPollInterval = max
+		return nil
+	})
+}
+
+// WithPinProgress sets a function that is called by PinAndWait with each pin
+// request status retrieved.
+func WithPinProgress(progress func(*PinResponse)) PinWaitOption {
+	return pinWaitOption(func(cfg *pinWaitConfig) error {
+		cfg.progress = progress
+		return nil
+	})
+}
+
+// ListPinsOption is an option configuring a call to ListPins.
+type ListPinsOption func(cfg *pinListConfig) error
+
+// WithPinCidFilter restricts the list to pin requests for the passed CIDs. Up to
+// 10 CIDs may be specified.
+func WithPinCidFilter(cids ...cid.Cid) ListPinsOption {
+	return func(cfg *pinListConfig) error {
+		cfg.cids = append(cfg.cids, cids...)
+		if len(cfg.cids) > 10 {
+			return fmt.Errorf("too many CIDs in filter: %d", len(cfg.cids))
+		}
+		return nil
+	}
+}
+
+// WithPinNameFilter restricts the list to pin requests with the passed name.
+// By default names must match exactly, use WithPinNameMatch to change this.
+func WithPinNameFilter(name string) ListPinsOption {
+	return func(cfg *pinListConfig) error {
+		cfg.name = name
+		return nil
+	}
+}
+
+// WithPinNameMatch sets the name matching strategy, one of PinNameMatchExact
+// (the default), PinNameMatchIExact, PinNameMatchPartial or
+// PinNameMatchIPartial.
+func WithPinNameMatch(match string) ListPinsOption {
+	return func(cfg *pinListConfig) error {
+		switch match {
+		case PinNameMatchExact, PinNameMatchIExact, PinNameMatchPartial, PinNameMatchIPartial:
+			cfg.match = match
+		default:
+			return fmt.Errorf("invalid name match: %s", match)
+		}
+		return nil
+	}
+}
+
+// WithPinStatusFilter restricts the list to pin requests with one of the passed
+// statuses. The default is to list pinned items only.
+func WithPinStatusFilter(statuses ...PinRequestStatus) ListPinsOption {
+	return func(cfg *pinListConfig) error {
+		cfg.statuses = append(cfg.statuses, statuses...)
+		return nil
+	}
+}
+
+// WithPinsBefore sets the time that pin requests in the list were created
+// before.
+func WithPinsBefore(before time.Time) ListPinsOption {
+	return func(cfg *pinListConfig) error {
+		cfg.before = before
+		return nil
+	}
+}
+
+// WithPinsAfter sets the time that pin requests in the list were created after.
+func WithPinsAfter(after time.Time) ListPinsOption {
+	return func(cfg *pinListConfig) error {
+		cfg.after = after
+		return nil
+	}
+}
+
+// WithPinMetaFilter restricts the list to pin requests with metadata matching
+// the passed key and value.
+func WithPinMetaFilter(key, value string) ListPinsOption {
+	return func(cfg *pinListConfig) error {
+		if cfg.meta == nil {
+			cfg.meta = map[string]string{}
+		}
+		cfg.meta[key] = value
+		return nil
+	}
+}
+
+// WithPinPageSize sets the number of pin requests retrieved per request made
+// to the API (default 10, maximum 1000).
+func WithPinPageSize(size int) ListPinsOption {
+	return func(cfg *pinListConfig) error {
+		if size > maxPinsPageSize {
+			size = maxPinsPageSize
+		}
+		cfg.pageSize = size
+		return nil
+	}
+}
@@ -4,16 +4,42 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ipfs/go-cid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// PinRequestStatus is the status of a pin request made to the pinning service
+// API. Not to be confused with PinStatus, which is the status of the data on a
+// particular IPFS Cluster peer.
+type PinRequestStatus string
+
+const (
+	PinRequestQueued  = PinRequestStatus("queued")
+	PinRequestPinning = PinRequestStatus("pinning")
+	PinRequestPinned  = PinRequestStatus("pinned")
+	PinRequestFailed  = PinRequestStatus("failed")
+)
+
+// Done reports whether the status is final i.e. the data has been pinned or
+// pinning failed.
+func (s PinRequestStatus) Done() bool {
+	return s == PinRequestPinned || s == PinRequestFailed
+}
+
+// ErrPinFailed is returned by PinAndWait when the pin request failed.
+var ErrPinFailed = errors.New("pin request failed")
+
 type PinResponse struct {
 	RequestID string
-	Status    string
+	Status    PinRequestStatus
 	Created   time.Time
 	Pin       PinResponseDetail
 	Delegates []string
@@ -34,7 +60,7 @@ func (p *PinResponse) UnmarshalJSON(b []byte) error {
 		return err
 	}
 	p.RequestID = raw.RequestID
-	p.Status = raw.Status
+	p.Status = PinRequestStatus(raw.Status)
 	if raw.Created != "" {
 		p.Created, err = time.Parse(iso8601, raw.Created)
 		if err != nil {
@@ -143,8 +169,124 @@ type pinConfig struct {
 	meta    map[string]string
 }
 
+type pinWaitConfig struct {
+	pin             []PinOption
+	timeout         time.Duration
+	pollInterval    time.Duration
+	maxPollInterval time.Duration
+	progress        func(*PinResponse)
+}
+
+const (
+	defaultPinPollInterval    = time.Second
+	defaultPinMaxPollInterval = time.Second * 30
+)
+
 // Pin adds a new pin to Web3.Storage.
 func (c *client) Pin(ctx context.Context, cid cid.Cid, options ...PinOption) (*PinResponse, error) {
+	body, err := encodePinRequest(cid, options)
+	if err != nil {
+		return nil, err
+	}
+	return c.sendPinRequest(ctx, "Pin", "POST", "/pins", body)
+}
+
+// PinAndWait adds a new pin to Web3.Storage and polls the pin request until the
+// data is pinned or pinning fails. The interval between polls doubles after
+// each one, up to a maximum. If pinning fails then the last response is
+// returned along with ErrPinFailed.
+func (c *client) PinAndWait(ctx context.Context, cid cid.Cid, options ...PinWaitOption) (*PinResponse, error) {
+	cfg := pinWaitConfig{
+		pollInterval:    defaultPinPollInterval,
+		maxPollInterval: defaultPinMaxPollInterval,
+	}
+	for _, opt := range options {
+		if err := opt.applyPinWait(&cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+
+	pr, err := c.Pin(ctx, cid, cfg.pin...)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := cfg.pollInterval
+	for {
+		if cfg.progress != nil {
+			cfg.progress(pr)
+		}
+		if pr.Status == PinRequestFailed {
+			return pr, fmt.Errorf("%w: %s", ErrPinFailed, pr.RequestID)
+		}
+		if pr.Status.Done() {
+			return pr, nil
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return pr, fmt.Errorf("waiting for pin request %s: %w", pr.RequestID, ctx.Err())
+		case <-timer.C:
+		}
+
+		interval *= 2
+		if interval > cfg.maxPollInterval {
+			interval = cfg.maxPollInterval
+		}
+
+		next, err := c.GetPin(ctx, pr.RequestID)
+		if err != nil {
+			return pr, err
+		}
+		pr = next
+	}
+}
+
+// GetPin retrieves the pin request with the passed ID.
+func (c *client) GetPin(ctx context.Context, requestID string) (*PinResponse, error) {
+	return c.sendPinRequest(ctx, "GetPin", "GET", "/pins/"+url.PathEscape(requestID), nil)
+}
+
+// ReplacePin replaces the pin request with the passed ID by a pin for the
+// passed CID. Note that the returned pin request has a new ID.
+func (c *client) ReplacePin(ctx context.Context, requestID string, cid cid.Cid, options ...PinOption) (*PinResponse, error) {
+	body, err := encodePinRequest(cid, options)
+	if err != nil {
+		return nil, err
+	}
+	return c.sendPinRequest(ctx, "ReplacePin", "POST", "/pins/"+url.PathEscape(requestID), body)
+}
+
+// DeletePin removes the pin request with the passed ID.
+func (c *client) DeletePin(ctx context.Context, requestID string) (err error) {
+	ctx, span := c.startSpan(ctx, "DeletePin", attribute.String("requestId", requestID))
+	defer func() { endSpan(span, err) }()
+
+	req, err := c.newRequest(ctx, "DELETE", "/pins/"+url.PathEscape(requestID), nil)
+	if err != nil {
+		return err
+	}
+	res, err := c.cfg.hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("send delete pin request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 && res.StatusCode != 202 {
+		return newResponseError(res)
+	}
+	return nil
+}
+
+func encodePinRequest(cid cid.Cid, options []PinOption) (io.Reader, error) {
 	var cfg pinConfig
 	for _, opt := range options {
 		if err := opt(&cfg); err != nil {
@@ -169,22 +311,30 @@ func (c *client) Pin(ctx context.Context, cid cid.Cid, options ...PinOption) (*P
 	if err != nil {
 		return nil, fmt.Errorf("encode pin request: %w", err)
 	}
+	return encoded, nil
+}
+
+// sendPinRequest sends a request to the pinning service API that responds with
+// a pin request status. The operation name is used to name the trace span.
+func (c *client) sendPinRequest(ctx context.Context, operation, method, path string, body io.Reader) (_ *PinResponse, err error) {
+	ctx, span := c.startSpan(ctx, operation)
+	defer func() { endSpan(span, err) }()
 
-	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/pins", c.cfg.endpoint), encoded)
+	req, err := c.newRequest(ctx, method, path, body)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.cfg.token))
-	req.Header.Add("X-Client", clientName)
 	res, err := c.cfg.hc.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("send pin request: %w", err)
 	}
+	defer res.Body.Close()
 
-	if res.StatusCode != 200 {
-		return nil, fmt.Errorf("unexpected response status: %d", res.StatusCode)
+	// The pinning service API specifies 202 for new pin requests but
+	// Web3.Storage responds with 200.
+	if res.StatusCode != 200 && res.StatusCode != 202 {
+		return nil, newResponseError(res)
 	}
-	defer res.Body.Close()
 
 	d := json.NewDecoder(res.Body)
 
@@ -193,5 +343,232 @@ func (c *client) Pin(ctx context.Context, cid cid.Cid, options ...PinOption) (*P
 	if err != nil {
 		return nil, fmt.Errorf("decode pin response: %w", err)
 	}
+	span.SetAttributes(
+		attribute.String("cid", pr.Pin.Cid.String()),
+		attribute.String("requestId", pr.RequestID),
+		attribute.String("status", string(pr.Status)),
+	)
 	return &pr, nil
 }
+
+const maxPinsPageSize = 1000
+
+// Values accepted by WithPinNameMatch.
+const (
+	PinNameMatchExact    = "exact"
+	PinNameMatchIExact   = "iexact"
+	PinNameMatchPartial  = "partial"
+	PinNameMatchIPartial = "ipartial"
+)
+
+// PinIterator iterates over the pin requests returned by a call to ListPins.
+type PinIterator struct {
+	fetchPage func(ctx context.Context, before time.Time, limit int) (*pinsPage, error)
+	before    time.Time
+	// limit is the number of pin requests requested per page, or zero for the
+	// service default.
+	limit int
+	// overlap is set when the next page should include pin requests created
+	// at the before time, as there may be more than were in the last page.
+	overlap bool
+	// seen holds the request IDs of the last page, to skip them if they are
+	// listed again in an overlapping page.
+	seen  map[string]bool
+	count int
+	page  []*PinResponse
+	done  bool
+}
+
+type pinsPage struct {
+	Count   int            `json:"count"`
+	Results []*PinResponse `json:"results"`
+}
+
+// NewPinIterator creates an iterator over a fixed list of pin requests. It is
+// intended for use by fakes of the Client interface.
+func NewPinIterator(items []*PinResponse) *PinIterator {
+	fetched := false
+	fetchPage := func(ctx context.Context, before time.Time, limit int) (*pinsPage, error) {
+		if fetched {
+			return &pinsPage{Count: len(items)}, nil
+		}
+		fetched = true
+		return &pinsPage{Count: len(items), Results: items}, nil
+	}
+	return &PinIterator{fetchPage: fetchPage}
+}
+
+// Next retrieves the next pin request in the list, making any request with the
+// passed context. It returns io.EOF when there are no more pin requests.
+func (pi *PinIterator) Next(ctx context.Context) (*PinResponse, error) {
+	if err := pi.fill(ctx); err != nil {
+		return nil, err
+	}
+	item := pi.page[0]
+	pi.page = pi.page[1:]
+	return item, nil
+}
+
+// fill fetches pages until there is at least one pin request not yet returned
+// by Next.
+func (pi *PinIterator) fill(ctx context.Context) error {
+	for len(pi.page) == 0 {
+		if pi.done {
+			return io.EOF
+		}
+		before := pi.before
+		if pi.overlap {
+			// The before filter is exclusive and times have millisecond
+			// precision, so this includes those created at the same time.
+			before = before.Truncate(time.Millisecond).Add(time.Millisecond)
+		}
+		p, err := pi.fetchPage(ctx, before, pi.limit)
+		if err != nil {
+			return err
+		}
+		pi.count = p.Count
+		if len(p.Results) == 0 {
+			pi.done = true
+			return io.EOF
+		}
+		var fresh []*PinResponse
+		for _, r := range p.Results {
+			if !pi.seen[r.RequestID] {
+				fresh = append(fresh, r)
+			}
+		}
+		if len(fresh) == 0 {
+			if !pi.overlap {
+				pi.done = true
+				return io.EOF
+			}
+			if pi.limit != maxPinsPageSize {
+				// A whole page was created at the same time, so ask for the
+				// largest page to get past them.
+				pi.limit = maxPinsPageSize
+				continue
+			}
+			if len(p.Results) < maxPinsPageSize {
+				pi.done = true
+				return io.EOF
+			}
+			return fmt.Errorf("more than %d pin requests were created at %s", maxPinsPageSize, pi.before.Format(iso8601))
+		}
+		pi.seen = make(map[string]bool, len(p.Results))
+		for _, r := range p.Results {
+			pi.seen[r.RequestID] = true
+		}
+		pi.page = fresh
+		// Pin requests are listed newest first so the next page is those
+		// created before the last item in this one, or at the same time.
+		pi.before = p.Results[len(p.Results)-1].Created
+		pi.overlap = true
+	}
+	return nil
+}
+
+// Count returns the total number of pin requests matching the filters, as
+// reported by the service with the most recently fetched page of results.
+func (pi *PinIterator) Count() int {
+	return pi.count
+}
+
+type pinListConfig struct {
+	cids     []cid.Cid
+	name     string
+	match    string
+	statuses []PinRequestStatus
+	before   time.Time
+	after    time.Time
+	meta     map[string]string
+	pageSize int
+}
+
+// ListPins retrieves the list of pin requests made to Web3.Storage. The first
+// page of results is requested with the passed context and later pages with
+// the context passed to PinIterator.Next.
+//
+// Pages are requested by creation time, which has millisecond precision. When
+// a page ends part way through the pin requests created in one millisecond,
+// the next page starts from that millisecond again. Iteration fails with an
+// error if more than 1000 pin requests, the largest page size, were created
+// in the same millisecond.
+func (c *client) ListPins(ctx context.Context, options ...ListPinsOption) (*PinIterator, error) {
+	var cfg pinListConfig
+	for _, opt := range options {
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	query := url.Values{}
+	if len(cfg.cids) > 0 {
+		var cids []string
+		for _, c := range cfg.cids {
+			cids = append(cids, c.String())
+		}
+		query.Set("cid", strings.Join(cids, ","))
+	}
+	if cfg.name != "" {
+		query.Set("name", cfg.name)
+	}
+	if cfg.match != "" {
+		query.Set("match", cfg.match)
+	}
+	if len(cfg.statuses) > 0 {
+		var statuses []string
+		for _, s := range cfg.statuses {
+			statuses = append(statuses, string(s))
+		}
+		query.Set("status", strings.Join(statuses, ","))
+	}
+	if !cfg.after.IsZero() {
+		query.Set("after", cfg.after.Format(iso8601))
+	}
+	if len(cfg.meta) > 0 {
+		meta, err := json.Marshal(cfg.meta)
+		if err != nil {
+			return nil, fmt.Errorf("encode meta filter: %w", err)
+		}
+		query.Set("meta", string(meta))
+	}
+	fetchPage := func(ctx context.Context, before time.Time, limit int) (*pinsPage, error) {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		if limit > 0 {
+			q.Set("limit", strconv.Itoa(limit))
+		}
+		if !before.IsZero() {
+			q.Set("before", before.Format(iso8601))
+		}
+		req, err := c.newRequest(ctx, "GET", "/pins?"+q.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		res, err := c.cfg.hc.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("send list pins request: %w", err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != 200 {
+			return nil, newResponseError(res)
+		}
+
+		var p pinsPage
+		d := json.NewDecoder(res.Body)
+		err = d.Decode(&p)
+		if err != nil {
+			return nil, fmt.Errorf("decode list pins response: %w", err)
+		}
+		return &p, nil
+	}
+
+	it := &PinIterator{fetchPage: fetchPage, before: cfg.before, limit: cfg.pageSize}
+	if err := it.fill(ctx); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return it, nil
+}
@@ -4,8 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ipfs/go-cid"
 )
@@ -67,3 +73,347 @@ func TestPinsHappyPath(t *testing.T) {
 		t.Fatalf("got cid %s, wanted %s", pr.Pin.Cid.String(), helloRoot)
 	}
 }
+
+var pinRequestHandler = func(w http.ResponseWriter, r *http.Request) {
+	if !hasValidToken(w, r) {
+		return
+	}
+
+	requestID := strings.TrimPrefix(r.URL.Path, "/pins/")
+	if requestID != "pin-"+helloRoot {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusAccepted)
+		return
+	case http.MethodPost:
+		body := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requestID = fmt.Sprintf("pin-%s", body["cid"])
+	}
+
+	resp := map[string]interface{}{
+		"requestId": requestID,
+		"status":    "pinned",
+		"created":   "2022-02-20T15:04:05.999Z",
+		"pin": map[string]interface{}{
+			"cid": strings.TrimPrefix(requestID, "pin-"),
+		},
+		"delegates": []interface{}{},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func TestPinRequestLifecycle(t *testing.T) {
+	routes := routeMap{
+		"/pins/": {
+			http.MethodGet:    pinRequestHandler,
+			http.MethodPost:   pinRequestHandler,
+			http.MethodDelete: pinRequestHandler,
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	pr, err := client.GetPin(context.Background(), "pin-"+helloRoot)
+	if err != nil {
+		t.Fatalf("failed to get pin: %v", err)
+	}
+
+	if pr.Pin.Cid.String() != helloRoot {
+		t.Fatalf("got cid %s, wanted %s", pr.Pin.Cid.String(), helloRoot)
+	}
+
+	c, _ := cid.Parse(thanksRoot)
+	pr, err = client.ReplacePin(context.Background(), pr.RequestID, c)
+	if err != nil {
+		t.Fatalf("failed to replace pin: %v", err)
+	}
+
+	if pr.RequestID != "pin-"+thanksRoot {
+		t.Fatalf("got request ID %s, wanted %s", pr.RequestID, "pin-"+thanksRoot)
+	}
+
+	err = client.DeletePin(context.Background(), "pin-"+helloRoot)
+	if err != nil {
+		t.Fatalf("failed to delete pin: %v", err)
+	}
+
+	_, err = client.GetPin(context.Background(), "pin-unknown")
+	if err == nil {
+		t.Fatalf("expected error getting unknown pin")
+	}
+}
+
+var listPinsHandler = func(w http.ResponseWriter, r *http.Request) {
+	if !hasValidToken(w, r) {
+		return
+	}
+
+	results := []interface{}{}
+	if r.URL.Query().Get("before") == "" {
+		for i, c := range []string{helloRoot, thanksRoot} {
+			results = append(results, map[string]interface{}{
+				"requestId": "pin-" + c,
+				"status":    "pinned",
+				"created":   fmt.Sprintf("2022-02-2%dT15:04:05.999Z", 2-i),
+				"pin":       map[string]interface{}{"cid": c},
+				"delegates": []interface{}{},
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"count":   2,
+		"results": results,
+	})
+}
+
+func TestListPinsHappyPath(t *testing.T) {
+	var query url.Values
+	routes := routeMap{
+		"/pins": {
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) {
+				if query == nil {
+					query = r.URL.Query()
+				}
+				listPinsHandler(w, r)
+			},
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	it, err := client.ListPins(
+		context.Background(),
+		WithPinStatusFilter(PinRequestPinned, PinRequestQueued),
+		WithPinNameFilter("hello"),
+		WithPinNameMatch(PinNameMatchPartial),
+		WithPinMetaFilter("app", "test"),
+	)
+	if err != nil {
+		t.Fatalf("failed to list pins: %v", err)
+	}
+
+	var n int
+	for {
+		_, err := it.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to get next pin: %v", err)
+		}
+		n++
+	}
+
+	if n != 2 || it.Count() != 2 {
+		t.Fatalf("got %d pins (count %d), wanted %d", n, it.Count(), 2)
+	}
+
+	for k, v := range map[string]string{"status": "pinned,queued", "name": "hello", "match": "partial", "meta": `{"app":"test"}`} {
+		if query.Get(k) != v {
+			t.Fatalf("got query param %s=%s, wanted %s", k, query.Get(k), v)
+		}
+	}
+}
+
+func TestPinAndWait(t *testing.T) {
+	var polls int32
+	routes := routeMap{
+		"/pins": {
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) {
+				writeQueuedPin(w, "pin-"+helloRoot, "queued")
+			},
+		},
+		"/pins/": {
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) {
+				status := "queued"
+				if atomic.AddInt32(&polls, 1) > 1 {
+					status = "pinned"
+				}
+				writeQueuedPin(w, strings.TrimPrefix(r.URL.Path, "/pins/"), status)
+			},
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	c, _ := cid.Parse(helloRoot)
+
+	var statuses []PinRequestStatus
+	pr, err := client.PinAndWait(
+		context.Background(),
+		c,
+		WithPinPollInterval(time.Millisecond, time.Millisecond*5),
+		WithPinWaitTimeout(time.Second*5),
+		WithPinProgress(func(pr *PinResponse) {
+			statuses = append(statuses, pr.Status)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to pin and wait: %v", err)
+	}
+
+	if pr.Status != PinRequestPinned {
+		t.Fatalf("got status %s, wanted %s", pr.Status, PinRequestPinned)
+	}
+
+	if len(statuses) != 3 || polls != 2 {
+		t.Fatalf("got %d progress updates and %d polls, wanted 3 and 2", len(statuses), polls)
+	}
+}
+
+func writeQueuedPin(w http.ResponseWriter, requestID, status string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"requestId": requestID,
+		"status":    status,
+		"created":   "2022-02-20T15:04:05.999Z",
+		"pin":       map[string]interface{}{"cid": helloRoot},
+		"delegates": []interface{}{},
+	})
+}
+
+type testPin struct{ id, created string }
+
+// listPinsAt lists the pins, ordered newest first, with the before and limit
+// parameters applied as the service does.
+func listPinsAt(t *testing.T, pins []testPin, pageSize int) ([]string, error) {
+	routes := routeMap{
+		"/pins": {
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) {
+				var before time.Time
+				if v := r.URL.Query().Get("before"); v != "" {
+					before, _ = time.Parse(iso8601, v)
+				}
+				limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+				results := []interface{}{}
+				for _, p := range pins {
+					created, _ := time.Parse(iso8601, p.created)
+					if !before.IsZero() && !created.Before(before) {
+						continue
+					}
+					if len(results) == limit {
+						break
+					}
+					results = append(results, map[string]interface{}{
+						"requestId": p.id,
+						"status":    "pinned",
+						"created":   p.created,
+						"pin":       map[string]interface{}{"cid": helloRoot},
+						"delegates": []interface{}{},
+					})
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"count":   len(pins),
+					"results": results,
+				})
+			},
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	it, err := client.ListPins(context.Background(), WithPinPageSize(pageSize))
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for {
+		p, err := it.Next(context.Background())
+		if err == io.EOF {
+			return ids, nil
+		}
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, p.RequestID)
+	}
+}
+
+func TestListPinsSharedTimestamps(t *testing.T) {
+	// Two pins share a timestamp that falls on the boundary of the first page.
+	pins := []testPin{
+		{"pin-1", "2022-02-22T15:04:05.003Z"},
+		{"pin-2", "2022-02-22T15:04:05.002Z"},
+		{"pin-3", "2022-02-22T15:04:05.002Z"},
+		{"pin-4", "2022-02-22T15:04:05.001Z"},
+	}
+	ids, err := listPinsAt(t, pins, 2)
+	if err != nil {
+		t.Fatalf("failed to list pins: %v", err)
+	}
+	if got, wanted := strings.Join(ids, ","), "pin-1,pin-2,pin-3,pin-4"; got != wanted {
+		t.Fatalf("got pins %s, wanted %s", got, wanted)
+	}
+}
+
+func TestListPinsPageSharingTimestamp(t *testing.T) {
+	// More pins share a timestamp than fit in a page.
+	pins := []testPin{
+		{"pin-1", "2022-02-22T15:04:05.002Z"},
+		{"pin-2", "2022-02-22T15:04:05.001Z"},
+		{"pin-3", "2022-02-22T15:04:05.001Z"},
+		{"pin-4", "2022-02-22T15:04:05.001Z"},
+		{"pin-5", "2022-02-22T15:04:05.001Z"},
+		{"pin-6", "2022-02-22T15:04:05.000Z"},
+	}
+	ids, err := listPinsAt(t, pins, 2)
+	if err != nil {
+		t.Fatalf("failed to list pins: %v", err)
+	}
+	if got, wanted := strings.Join(ids, ","), "pin-1,pin-2,pin-3,pin-4,pin-5,pin-6"; got != wanted {
+		t.Fatalf("got pins %s, wanted %s", got, wanted)
+	}
+}
+
+func TestListPinsTooManySharingTimestamp(t *testing.T) {
+	var pins []testPin
+	for i := 0; i <= maxPinsPageSize; i++ {
+		pins = append(pins, testPin{fmt.Sprintf("pin-%d", i), "2022-02-22T15:04:05.001Z"})
+	}
+	pins = append(pins, testPin{"pin-last", "2022-02-22T15:04:05.000Z"})
+	ids, err := listPinsAt(t, pins, 2)
+	if err == nil {
+		t.Fatalf("got %d pins, wanted an error", len(ids))
+	}
+}
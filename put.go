@@ -1,18 +1,27 @@
 package w3s
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
-	"net/http"
+	"io/ioutil"
+	"net/url"
+	"time"
 
 	"github.com/alanshaw/go-carbites"
+	bserv "github.com/ipfs/go-blockservice"
 	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
 	"github.com/ipfs/go-merkledag"
 	"github.com/ipld/go-car"
+	carv2 "github.com/ipld/go-car/v2"
 	"github.com/web3-storage/go-w3s-client/adder"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const targetChunkSize = 1024 * 1024 * 10
@@ -20,112 +29,301 @@ const targetChunkSize = 1024 * 1024 * 10
 type putConfig struct {
 	fsys    fs.FS
 	dirname string
+	name    string
+	quota   bool
+	files   []fs.File
 }
 
 // Put uploads files to Web3.Storage. The file argument can be a single file or
 // a directory. If a directory is passed and the directory does NOT implement
 // fs.ReadDirFile then the WithDirname option should be passed (or the current
-// process working directory will be used).
-func (c *client) Put(ctx context.Context, file fs.File, options ...PutOption) (cid.Cid, error) {
+// process working directory will be used). Further files may be uploaded in
+// the same call with WithFiles, each as a separate root. The root CID of each
+// file is returned, in order, starting with that of file.
+func (c *client) Put(ctx context.Context, file fs.File, options ...PutOption) (roots []cid.Cid, err error) {
+	ctx, span := c.startSpan(ctx, "Put")
+	defer func() { endSpan(span, err) }()
+
 	var cfg putConfig
 	for _, opt := range options {
 		if err := opt(&cfg); err != nil {
-			return cid.Undef, err
+			return nil, err
 		}
 	}
+	files := append([]fs.File{file}, cfg.files...)
+	span.SetAttributes(attribute.Int("files", len(files)))
 
-	info, err := file.Stat()
-	if err != nil {
-		return cid.Undef, err
+	bsvc, done := c.trackUpload(ctx, c.blockService())
+	defer func() { done(err) }()
+	dag := merkledag.NewDAGService(bsvc)
+	for _, file := range files {
+		root, err := c.addFile(ctx, dag, file, &cfg)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, root)
+	}
+	span.SetAttributes(attribute.String("cid", roots[0].String()))
+
+	if cfg.quota {
+		err = c.checkQuota(ctx, dag, roots)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	dag := merkledag.NewDAGService(c.bsvc)
-	dagFmtr, err := adder.NewAdder(ctx, dag)
+	if len(roots) > 1 {
+		// carbites splits the DAG of a single root, so the DAGs are packed
+		// into shards with every root in their header as they are walked.
+		blocks, err := c.putDelta(ctx, dag, roots, nil, cfg.name)
+		if err != nil {
+			return nil, err
+		}
+		span.SetAttributes(attribute.Int("blocks", blocks))
+		return roots, nil
+	}
+
+	// The root is split into shards directly from the blocks just imported.
+	spltr, err := carbites.NewTreewalkSplitterFromBlockReader(roots[0], bsvc.Blockstore(), targetChunkSize)
 	if err != nil {
-		return cid.Undef, err
+		c.cfg.log.Errorf("splitting DAG %s: %v", roots[0], err)
+		return nil, err
 	}
+	var shard int
+	root, err := c.sendShards(ctx, spltr, &cfg, &shard)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("shards", shard))
+	return []cid.Cid{root}, nil
+}
+
+// addFile imports the file into the DAG service, returning the root CID.
+func (c *client) addFile(ctx context.Context, dag ipld.DAGService, file fs.File, cfg *putConfig, options ...adder.Option) (root cid.Cid, err error) {
+	ctx, span := c.startSpan(ctx, "Put.add")
+	defer func() {
+		if err == nil {
+			span.SetAttributes(attribute.String("cid", root.String()))
+		}
+		endSpan(span, err)
+	}()
 
-	root, err := dagFmtr.Add(file, cfg.dirname, cfg.fsys)
+	options = append([]adder.Option{adder.WithLogger(c.cfg.log)}, options...)
+	dagFmtr, err := adder.NewAdder(ctx, dag, options...)
 	if err != nil {
 		return cid.Undef, err
 	}
+	return dagFmtr.Import(file, cfg.dirname, cfg.fsys)
+}
 
-	// If file is a dir, do not wrap in another.
-	if info.IsDir() {
-		mr, err := dagFmtr.MfsRoot()
+// checkQuota returns ErrQuotaExceeded if the DAGs with the passed roots would
+// not fit in the storage remaining for the account.
+func (c *client) checkQuota(ctx context.Context, dag ipld.DAGService, roots []cid.Cid) error {
+	var size uint64
+	for _, root := range roots {
+		nd, err := dag.Get(ctx, root)
 		if err != nil {
-			return cid.Undef, err
+			return err
 		}
-		rdir := mr.GetDirectory()
-		cdir, err := rdir.Child(info.Name())
+		n, err := nd.Size()
 		if err != nil {
-			return cid.Undef, err
+			return err
 		}
-		cnode, err := cdir.GetNode()
-		if err != nil {
-			return cid.Undef, err
+		size += n
+	}
+	u, err := c.User(ctx)
+	if err != nil {
+		return fmt.Errorf("checking quota: %w", err)
+	}
+	remaining, ok := u.StorageRemaining()
+	if ok && size > remaining {
+		return fmt.Errorf("%w: upload is %d bytes but %d bytes remain", ErrQuotaExceeded, size, remaining)
+	}
+	return nil
+}
+
+// PutCar uploads a CAR (Content Addressable Archive) to Web3.Storage. The CAR
+// may be a CARv1 or a CARv2, whose data payload is uploaded. Options that
+// configure how files are read (WithFs, WithDirname and WithFiles) are
+// ignored. The roots of the CAR are returned. A CAR with several roots is
+// uploaded as one CAR with all of them in its header, holding the blocks
+// reachable from the roots.
+func (c *client) PutCar(ctx context.Context, r io.Reader, options ...PutOption) (roots []cid.Cid, err error) {
+	ctx, span := c.startSpan(ctx, "PutCar")
+	defer func() { endSpan(span, err) }()
+
+	var cfg putConfig
+	for _, opt := range options {
+		if err := opt(&cfg); err != nil {
+			return nil, err
 		}
-		root = cnode.Cid()
 	}
 
-	carReader, carWriter := io.Pipe()
+	h, r, err := peekCarHeader(r)
+	if err == nil && h.Version == 2 {
+		span.SetAttributes(attribute.Int64("version", 2))
+		r, err = carV2Data(r)
+		if err == nil {
+			h, r, err = peekCarHeader(r)
+		}
+	}
+	if err == nil && h.Version != 1 {
+		err = fmt.Errorf("unsupported CAR version %d", h.Version)
+	}
+	if err != nil {
+		c.cfg.log.Errorf("reading CAR: %v", err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("cid", h.Roots[0].String()), attribute.Int("roots", len(h.Roots)))
 
-	go func() {
-		err = car.WriteCar(ctx, dag, []cid.Cid{root}, carWriter)
+	if len(h.Roots) > 1 {
+		// carbites splits the DAG of a single root, so the blocks are loaded
+		// and packed into shards with every root in their header as the DAGs
+		// are walked.
+		bsvc, done := c.trackUpload(ctx, c.blockService())
+		defer func() { done(err) }()
+		if _, err := loadCar(ctx, bsvc, r); err != nil {
+			c.cfg.log.Errorf("reading CAR: %v", err)
+			return nil, err
+		}
+		blocks, err := c.putDelta(ctx, merkledag.NewDAGService(bsvc), h.Roots, nil, cfg.name)
 		if err != nil {
-			carWriter.CloseWithError(err)
-			return
+			return nil, err
 		}
-		carWriter.Close()
-	}()
+		span.SetAttributes(attribute.Int("blocks", blocks))
+		return h.Roots, nil
+	}
 
-	return c.PutCar(ctx, carReader)
+	spltr, err := carbites.Split(r, targetChunkSize, carbites.Treewalk)
+	if err != nil {
+		c.cfg.log.Errorf("splitting CAR: %v", err)
+		return nil, err
+	}
+	var shard int
+	root, err := c.sendShards(ctx, spltr, &cfg, &shard)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("shards", shard))
+	return []cid.Cid{root}, nil
 }
 
-// PutCar uploads a CAR (Content Addressable Archive) to Web3.Storage.
-func (c *client) PutCar(ctx context.Context, car io.Reader) (cid.Cid, error) {
-	spltr, err := carbites.Split(car, targetChunkSize, carbites.Treewalk)
+// peekCarHeader reads the header of a CAR, returning it and a reader of the
+// whole CAR including the header. The header of a CARv2 is its pragma, which
+// has version 2 and no roots.
+func peekCarHeader(r io.Reader) (*car.CarHeader, io.Reader, error) {
+	var read bytes.Buffer
+	h, err := car.ReadHeader(bufio.NewReader(io.TeeReader(r, &read)))
 	if err != nil {
-		return cid.Undef, err
+		return nil, nil, err
+	}
+	if h.Version == 1 && len(h.Roots) == 0 {
+		return nil, nil, errors.New("CAR has no roots")
+	}
+	return h, io.MultiReader(&read, r), nil
+}
+
+// carV2Data returns a reader of the data payload of a CARv2, which is a CARv1.
+// The index that may follow it is not needed to upload the CAR, so it is not
+// read.
+func carV2Data(r io.Reader) (io.Reader, error) {
+	// The pragma was checked by peekCarHeader.
+	if _, err := io.CopyN(ioutil.Discard, r, carv2.PragmaSize); err != nil {
+		return nil, err
 	}
+	var h carv2.Header
+	if _, err := h.ReadFrom(r); err != nil {
+		return nil, fmt.Errorf("reading CARv2 header: %w", err)
+	}
+	if h.DataOffset < carv2.PragmaSize+carv2.HeaderSize {
+		return nil, fmt.Errorf("invalid CARv2 data offset %d", h.DataOffset)
+	}
+	// Skip any padding before the data payload.
+	pad := int64(h.DataOffset) - carv2.PragmaSize - carv2.HeaderSize
+	if _, err := io.CopyN(ioutil.Discard, r, pad); err != nil {
+		return nil, err
+	}
+	return io.LimitReader(r, int64(h.DataSize)), nil
+}
 
+// loadCar reads the blocks of a CARv1 into the block service.
+func loadCar(ctx context.Context, bsvc bserv.BlockService, r io.Reader) (*car.CarReader, error) {
+	cr, err := car.NewCarReader(r)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		b, err := cr.Next()
+		if err == io.EOF {
+			return cr, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		err = bsvc.AddBlock(ctx, b)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sendShards uploads the CARs produced by the splitter, numbering them from
+// shard, and returns the root reported for the last one.
+func (c *client) sendShards(ctx context.Context, spltr carbites.Splitter, cfg *putConfig, shard *int) (cid.Cid, error) {
 	var root cid.Cid
 	for {
 		r, err := spltr.Next()
 		if err != nil {
 			if err == io.EOF {
-				break
+				return root, nil
 			}
+			c.cfg.log.Errorf("splitting CAR shard %d: %v", *shard, err)
 			return cid.Undef, err
 		}
 
 		// TODO: concurrency
-		c, err := c.sendCar(ctx, r)
+		root, err = c.sendCar(ctx, r, cfg, *shard)
 		if err != nil {
 			return cid.Undef, err
 		}
-		root = c
+		*shard++
 	}
-
-	return root, nil
 }
 
-// TODO: retry
-func (c *client) sendCar(ctx context.Context, r io.Reader) (cid.Cid, error) {
-	req, err := http.NewRequestWithContext(ctx, "POST", c.cfg.endpoint+"/car", r)
+func (c *client) sendCar(ctx context.Context, r io.Reader, cfg *putConfig, shard int) (root cid.Cid, err error) {
+	ctx, span := c.startSpan(ctx, "sendCar", attribute.Int("shard", shard))
+	defer func() { endSpan(span, err) }()
+
+	// Shards are buffered so the request can be retried.
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		c.cfg.log.Errorf("reading shard %d: %v", shard, err)
+		return cid.Undef, err
+	}
+	size := int64(len(b))
+	req, err := c.newRequest(ctx, "POST", "/car", bytes.NewReader(b))
 	if err != nil {
 		return cid.Undef, err
 	}
 	req.Header.Add("Content-Type", "application/car")
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.cfg.token))
-	req.Header.Add("X-Client", clientName)
+	if cfg.name != "" {
+		req.Header.Add("X-Name", url.PathEscape(cfg.name))
+	}
+	start := time.Now()
 	res, err := c.cfg.hc.Do(req)
 	if err != nil {
+		c.cfg.log.Errorf("uploading shard %d: %v", shard, err)
 		return cid.Undef, err
 	}
+	defer res.Body.Close()
+	span.SetAttributes(attribute.Int64("bytes", size))
 	if res.StatusCode != 200 {
-		return cid.Undef, fmt.Errorf("unexpected response status: %d", res.StatusCode)
+		err := newResponseError(res)
+		c.cfg.log.Errorf("uploading shard %d (%d bytes): %v", shard, size, err)
+		return cid.Undef, err
 	}
+	c.tel.recordShard(ctx, size, time.Since(start))
+	c.cfg.log.Debugf("uploaded shard %d (%d bytes) in %v", shard, size, time.Since(start))
 	d := json.NewDecoder(res.Body)
 	var out struct {
 		Cid string `json:"cid"`
@@ -134,5 +332,6 @@ func (c *client) sendCar(ctx context.Context, r io.Reader) (cid.Cid, error) {
 	if err != nil {
 		return cid.Undef, err
 	}
+	span.SetAttributes(attribute.String("cid", out.Cid))
 	return cid.Parse(out.Cid)
 }
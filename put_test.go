@@ -6,12 +6,45 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"testing/iotest"
 
+	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-car"
+	"github.com/ipld/go-car/util"
+	carv2 "github.com/ipld/go-car/v2"
+	"github.com/web3-storage/go-w3s-client/w3stest"
 )
 
+func newFakeClient(t *testing.T, srv *w3stest.Server, options ...Option) Client {
+	options = append([]Option{WithEndpoint(srv.URL), WithToken(validToken)}, options...)
+	client, err := NewClient(options...)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return client
+}
+
+func putFakeFile(t *testing.T, client Client, name, content string) cid.Cid {
+	fsys := fstest.MapFS{name: {Data: []byte(content)}}
+	f, err := fsys.Open(name)
+	if err != nil {
+		t.Fatalf("failed to open file: %v", err)
+	}
+	defer f.Close()
+	roots, err := client.Put(context.Background(), f, WithName(name))
+	if err != nil {
+		t.Fatalf("failed to put file: %v", err)
+	}
+	return roots[0]
+}
+
 func hasValidToken(w http.ResponseWriter, r *http.Request) bool {
 	auth := r.Header.Get("Authorization")
 	if auth != "Bearer "+validToken {
@@ -64,12 +97,254 @@ func TestPutCarHappyPath(t *testing.T) {
 		return
 	}
 
-	c, err := client.PutCar(context.Background(), bytes.NewReader(carbytes))
+	roots, err := client.PutCar(context.Background(), bytes.NewReader(carbytes))
 	if err != nil {
 		t.Fatalf("failed to send request: %v", err)
 	}
 
-	if c.String() != helloRoot {
-		t.Fatalf("got cid %s, wanted %s", c.String(), helloRoot)
+	if len(roots) != 1 || roots[0].String() != helloRoot {
+		t.Fatalf("got cids %v, wanted %s", roots, helloRoot)
+	}
+}
+
+func TestPutCarWithName(t *testing.T) {
+	var name string
+	routes := routeMap{
+		"/car": {
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) {
+				name = r.Header.Get("X-Name")
+				putCarHandler(w, r)
+			},
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken("validtoken"))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	carbytes, err := hex.DecodeString(helloCarHex)
+	if err != nil {
+		t.Fatalf("failed to decode car hex: %v", err)
+	}
+
+	_, err = client.PutCar(context.Background(), bytes.NewReader(carbytes), WithName("hello world"))
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+
+	if name != "hello%20world" {
+		t.Fatalf("got name %s, wanted %s", name, "hello%20world")
+	}
+}
+
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) logf(level, format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, level+" "+fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) {
+	l.logf("DEBUG", format, args...)
+}
+func (l *recordingLogger) Infof(format string, args ...interface{}) { l.logf("INFO", format, args...) }
+func (l *recordingLogger) Warnf(format string, args ...interface{}) { l.logf("WARN", format, args...) }
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.logf("ERROR", format, args...)
+}
+
+func TestPutCarLogsShardFailure(t *testing.T) {
+	routes := routeMap{
+		"/car": {
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	log := &recordingLogger{}
+	client, err := NewClient(WithHTTPClient(hc), WithToken("validtoken"), WithLogger(log))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	carbytes, err := hex.DecodeString(helloCarHex)
+	if err != nil {
+		t.Fatalf("failed to decode car hex: %v", err)
+	}
+
+	_, err = client.PutCar(context.Background(), bytes.NewReader(carbytes))
+	if err == nil {
+		t.Fatalf("got nil error, wanted upload failure")
+	}
+
+	want := "ERROR uploading shard 0"
+	for _, line := range log.lines {
+		if strings.HasPrefix(line, want) {
+			return
+		}
+	}
+	t.Fatalf("got log lines %q, wanted a line starting %q", log.lines, want)
+}
+
+// multiRootCar returns a CAR with the hello and thanks roots.
+func multiRootCar(t *testing.T) []byte {
+	var roots []cid.Cid
+	var blocks bytes.Buffer
+	for _, h := range []string{helloCarHex, thanksCarHex} {
+		b, err := hex.DecodeString(h)
+		if err != nil {
+			t.Fatalf("failed to decode car hex: %v", err)
+		}
+		cr, err := car.NewCarReader(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("failed to read car: %v", err)
+		}
+		roots = append(roots, cr.Header.Roots...)
+		for {
+			blk, err := cr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("failed to read block: %v", err)
+			}
+			if err := util.LdWrite(&blocks, blk.Cid().Bytes(), blk.RawData()); err != nil {
+				t.Fatalf("failed to write block: %v", err)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := car.WriteHeader(&car.CarHeader{Roots: roots, Version: 1}, &buf); err != nil {
+		t.Fatalf("failed to write header: %v", err)
+	}
+	buf.Write(blocks.Bytes())
+	return buf.Bytes()
+}
+
+func TestPutCarMultipleRoots(t *testing.T) {
+	srv := w3stest.NewServer(w3stest.WithToken(validToken))
+	defer srv.Close()
+	client := newFakeClient(t, srv)
+
+	roots, err := client.PutCar(context.Background(), bytes.NewReader(multiRootCar(t)))
+	if err != nil {
+		t.Fatalf("failed to put car: %v", err)
+	}
+	wanted := []string{helloRoot, thanksRoot}
+	if len(roots) != len(wanted) {
+		t.Fatalf("got %d roots, wanted %d", len(roots), len(wanted))
+	}
+	for i, root := range roots {
+		if root.String() != wanted[i] {
+			t.Fatalf("got root %s, wanted %s", root, wanted[i])
+		}
+		if !srv.HasUpload(root) {
+			t.Fatalf("upload %s not found", root)
+		}
+	}
+
+	// The roots are uploaded together in one CAR.
+	if srv.Requests() != 1 {
+		t.Fatalf("got %d requests, wanted %d", srv.Requests(), 1)
+	}
+}
+
+func TestPutWithFiles(t *testing.T) {
+	srv := w3stest.NewServer(w3stest.WithToken(validToken))
+	defer srv.Close()
+	client := newFakeClient(t, srv)
+
+	fsys := fstest.MapFS{
+		"a.txt":     {Data: []byte("aaa")},
+		"dir/b.txt": {Data: []byte("bbb")},
+	}
+	var files []fs.File
+	for _, name := range []string{"a.txt", "dir"} {
+		f, err := fsys.Open(name)
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", name, err)
+		}
+		defer f.Close()
+		files = append(files, f)
+	}
+	roots, err := client.Put(context.Background(), files[0], WithFiles(files[1:]...), WithFs(fsys))
+	if err != nil {
+		t.Fatalf("failed to put files: %v", err)
+	}
+	if len(roots) != 2 {
+		t.Fatalf("got %d roots, wanted %d", len(roots), 2)
+	}
+	// The files are uploaded together in one CAR.
+	if srv.Requests() != 1 {
+		t.Fatalf("got %d requests, wanted %d", srv.Requests(), 1)
+	}
+
+	for i, name := range []string{"/a.txt", "/b.txt"} {
+		if !srv.HasUpload(roots[i]) {
+			t.Fatalf("upload %s not found", roots[i])
+		}
+		res, err := client.Get(context.Background(), roots[i])
+		if err != nil {
+			t.Fatalf("failed to get: %v", err)
+		}
+		_, rfs, err := res.Files()
+		if err != nil {
+			t.Fatalf("failed to read files: %v", err)
+		}
+		if _, err := fs.Stat(rfs, name); err != nil {
+			t.Fatalf("failed to stat %s in root %d: %v", name, i, err)
+		}
+	}
+}
+
+func TestPutCarV2(t *testing.T) {
+	srv := w3stest.NewServer(w3stest.WithToken(validToken))
+	defer srv.Close()
+	client := newFakeClient(t, srv)
+
+	carbytes, err := hex.DecodeString(helloCarHex)
+	if err != nil {
+		t.Fatalf("failed to decode car hex: %v", err)
+	}
+	for _, tt := range []struct {
+		v1    []byte
+		roots []string
+	}{
+		{carbytes, []string{helloRoot}},
+		{multiRootCar(t), []string{helloRoot, thanksRoot}},
+	} {
+		var v2 bytes.Buffer
+		if err := carv2.WrapV1(bytes.NewReader(tt.v1), &v2); err != nil {
+			t.Fatalf("failed to wrap car: %v", err)
+		}
+
+		// The CAR is read as a stream, without seeking to its data payload.
+		roots, err := client.PutCar(context.Background(), iotest.OneByteReader(&v2))
+		if err != nil {
+			t.Fatalf("failed to put car: %v", err)
+		}
+		if len(roots) != len(tt.roots) {
+			t.Fatalf("got %d roots, wanted %d", len(roots), len(tt.roots))
+		}
+		for i, c := range roots {
+			if c.String() != tt.roots[i] {
+				t.Fatalf("got cid %s, wanted %s", c, tt.roots[i])
+			}
+			if !srv.HasUpload(c) {
+				t.Fatalf("upload %s not found", c)
+			}
+		}
 	}
 }
@@ -0,0 +1,50 @@
+package w3s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+)
+
+// RenameResult is the result of renaming an upload.
+type RenameResult struct {
+	Cid  cid.Cid
+	Name string
+}
+
+// Rename changes the name of an upload.
+func (c *client) Rename(ctx context.Context, cid cid.Cid, name string) (*RenameResult, error) {
+	encoded := new(bytes.Buffer)
+	err := json.NewEncoder(encoded).Encode(map[string]string{"name": name})
+	if err != nil {
+		return nil, fmt.Errorf("encode rename request: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("/user/uploads/%s/rename", cid), encoded)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	res, err := c.cfg.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return nil, newResponseError(res)
+	}
+
+	var raw struct {
+		Name string `json:"name"`
+	}
+	d := json.NewDecoder(res.Body)
+	err = d.Decode(&raw)
+	if err != nil {
+		return nil, fmt.Errorf("decode rename response: %w", err)
+	}
+	return &RenameResult{Cid: cid, Name: raw.Name}, nil
+}
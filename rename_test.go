@@ -0,0 +1,59 @@
+package w3s
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+)
+
+var renameUploadHandler = func(w http.ResponseWriter, r *http.Request) {
+	if !hasValidToken(w, r) {
+		return
+	}
+
+	if r.URL.Path != "/user/uploads/"+helloRoot+"/rename" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	body := map[string]string{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"name": body["name"]})
+}
+
+func TestRenameHappyPath(t *testing.T) {
+	routes := routeMap{
+		"/user/uploads/": {
+			http.MethodPost: renameUploadHandler,
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	c, _ := cid.Parse(helloRoot)
+
+	res, err := client.Rename(context.Background(), c, "greetings")
+	if err != nil {
+		t.Fatalf("failed to rename upload: %v", err)
+	}
+
+	if res.Name != "greetings" {
+		t.Fatalf("got name %s, wanted %s", res.Name, "greetings")
+	}
+}
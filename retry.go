@@ -0,0 +1,173 @@
+package w3s
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/web3-storage/go-w3s-client/logging"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	defaultRetryMinBackoff = time.Second
+	defaultRetryMaxBackoff = 30 * time.Second
+)
+
+// defaultRetryEndpoint is retried although its method is not idempotent, as
+// uploads are content addressed so sending one again has the same result.
+const defaultRetryEndpoint = "POST /car"
+
+// retryConfig configures how failed requests are retried.
+type retryConfig struct {
+	max        int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	// endpoints are the patterns of requests with methods that are not
+	// idempotent that may be retried.
+	endpoints []string
+}
+
+// retryTransport retries requests that fail with a network error, a 429 or a
+// 5xx gateway/availability status. Only requests with an idempotent method or
+// matching one of the configured endpoints, and whose body can be replayed,
+// are retried.
+type retryTransport struct {
+	next      http.RoundTripper
+	cfg       retryConfig
+	basePath  string
+	endpoints []endpointPattern
+	tel       *telemetry
+	log       logging.Logger
+}
+
+func newRetryTransport(next http.RoundTripper, basePath string, cfg retryConfig, tel *telemetry, log logging.Logger) http.RoundTripper {
+	if cfg.max <= 0 {
+		return next
+	}
+	if cfg.minBackoff <= 0 {
+		cfg.minBackoff = defaultRetryMinBackoff
+	}
+	if cfg.maxBackoff < cfg.minBackoff {
+		cfg.maxBackoff = defaultRetryMaxBackoff
+		if cfg.maxBackoff < cfg.minBackoff {
+			cfg.maxBackoff = cfg.minBackoff
+		}
+	}
+	t := retryTransport{next: next, cfg: cfg, basePath: strings.TrimSuffix(basePath, "/"), tel: tel, log: log}
+	for _, pattern := range append([]string{defaultRetryEndpoint}, cfg.endpoints...) {
+		t.endpoints = append(t.endpoints, parseEndpointPattern(pattern))
+	}
+	return t
+}
+
+// canRetry reports whether sending the request again is safe, because its
+// method is idempotent or it matches an endpoint allowed to be retried.
+func (t retryTransport) canRetry(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	path := strings.TrimPrefix(req.URL.Path, t.basePath)
+	for _, p := range t.endpoints {
+		if p.matches(req.Method, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func retryable(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the delay requested by the Retry-After header of the
+// response, if any.
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func (t retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	replayable := (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil) && t.canRetry(req)
+	backoff := t.cfg.minBackoff
+	for attempt := 0; ; attempt++ {
+		res, err := t.next.RoundTrip(req)
+		if attempt >= t.cfg.max || !replayable || ctx.Err() != nil || !retryable(res, err) {
+			return res, err
+		}
+
+		delay := backoff
+		if d, ok := retryAfter(res); ok {
+			delay = d
+		}
+		if delay > t.cfg.maxBackoff {
+			delay = t.cfg.maxBackoff
+		}
+		if err != nil {
+			t.log.Warnf("%s %s failed, retrying in %v: %v", req.Method, req.URL.Path, delay, err)
+		} else {
+			t.log.Warnf("%s %s returned %d, retrying in %v", req.Method, req.URL.Path, res.StatusCode, delay)
+			io.Copy(ioutil.Discard, io.LimitReader(res.Body, 1024*64))
+			res.Body.Close()
+		}
+		t.tel.retries.Add(ctx, 1, attribute.String("http.method", req.Method))
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+		if backoff > t.cfg.maxBackoff {
+			backoff = t.cfg.maxBackoff
+		}
+
+		req, err = rewind(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// rewind returns a copy of the request with a fresh body.
+func rewind(ctx context.Context, req *http.Request) (*http.Request, error) {
+	r := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
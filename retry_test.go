@@ -0,0 +1,160 @@
+package w3s
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+)
+
+// failingServer starts a server that responds to requests for the pattern
+// with the passed status, recording when each request was received.
+func failingServer(t *testing.T, method, pattern string, status int, header http.Header) (*http.Client, func() []time.Time, func()) {
+	var mu sync.Mutex
+	var attempts []time.Time
+	routes := routeMap{
+		pattern: {
+			method: func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				attempts = append(attempts, time.Now())
+				mu.Unlock()
+				for k, v := range header {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(status)
+			},
+		},
+	}
+	hc, cleanup := startTestServer(t, routes)
+	return hc, func() []time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]time.Time{}, attempts...)
+	}, cleanup
+}
+
+func TestRetryCount(t *testing.T) {
+	hc, attempts, cleanup := failingServer(t, http.MethodGet, "/status/", http.StatusServiceUnavailable, nil)
+	defer cleanup()
+
+	client, err := NewClient(
+		WithHTTPClient(hc),
+		WithToken(validToken),
+		WithMaxRetries(2),
+		WithRetryBackoff(time.Millisecond, time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	c, _ := cid.Parse(helloRoot)
+	if _, err := client.Status(context.Background(), c); err == nil {
+		t.Fatalf("got nil error, wanted service unavailable error")
+	}
+	if n := len(attempts()); n != 3 {
+		t.Fatalf("got %d attempts, wanted %d", n, 3)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	hc, attempts, cleanup := failingServer(t, http.MethodGet, "/status/", http.StatusBadGateway, nil)
+	defer cleanup()
+
+	client, err := NewClient(
+		WithHTTPClient(hc),
+		WithToken(validToken),
+		WithMaxRetries(3),
+		WithRetryBackoff(20*time.Millisecond, 30*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	c, _ := cid.Parse(helloRoot)
+	client.Status(context.Background(), c)
+
+	times := attempts()
+	if len(times) != 4 {
+		t.Fatalf("got %d attempts, wanted %d", len(times), 4)
+	}
+	// The delay doubles from the minimum up to the maximum.
+	for i, min := range []time.Duration{20 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond} {
+		if d := times[i+1].Sub(times[i]); d < min || d > min+time.Second {
+			t.Fatalf("got delay %v before retry %d, wanted %v", d, i+1, min)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	header := http.Header{"Retry-After": []string{"1"}}
+	hc, attempts, cleanup := failingServer(t, http.MethodGet, "/status/", http.StatusTooManyRequests, header)
+	defer cleanup()
+
+	client, err := NewClient(
+		WithHTTPClient(hc),
+		WithToken(validToken),
+		WithMaxRetries(1),
+		WithRetryBackoff(time.Millisecond, 5*time.Second),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	c, _ := cid.Parse(helloRoot)
+	client.Status(context.Background(), c)
+
+	times := attempts()
+	if len(times) != 2 {
+		t.Fatalf("got %d attempts, wanted %d", len(times), 2)
+	}
+	if d := times[1].Sub(times[0]); d < time.Second {
+		t.Fatalf("got delay %v, wanted at least the %v requested by Retry-After", d, time.Second)
+	}
+
+	// The delay requested by the API is capped at the maximum backoff.
+	client, err = NewClient(
+		WithHTTPClient(hc),
+		WithToken(validToken),
+		WithMaxRetries(1),
+		WithRetryBackoff(time.Millisecond, 10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	start := time.Now()
+	client.Status(context.Background(), c)
+	if d := time.Since(start); d >= time.Second {
+		t.Fatalf("got delay %v, wanted it capped at %v", d, 10*time.Millisecond)
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	hc, attempts, cleanup := failingServer(t, http.MethodPost, "/pins", http.StatusServiceUnavailable, nil)
+	defer cleanup()
+
+	c, _ := cid.Parse(helloRoot)
+	pin := func(options ...Option) int {
+		options = append([]Option{
+			WithHTTPClient(hc),
+			WithToken(validToken),
+			WithMaxRetries(2),
+			WithRetryBackoff(time.Millisecond, time.Millisecond),
+		}, options...)
+		client, err := NewClient(options...)
+		if err != nil {
+			t.Fatalf("failed to create client: %v", err)
+		}
+		before := len(attempts())
+		if _, err := client.Pin(context.Background(), c); err == nil {
+			t.Fatalf("got nil error, wanted service unavailable error")
+		}
+		return len(attempts()) - before
+	}
+
+	if n := pin(); n != 1 {
+		t.Fatalf("got %d attempts, wanted %d", n, 1)
+	}
+	if n := pin(WithRetryEndpoint("POST /pins")); n != 3 {
+		t.Fatalf("got %d attempts with retries allowed, wanted %d", n, 3)
+	}
+}
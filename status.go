@@ -4,13 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"time"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs-cluster/ipfs-cluster/api"
 	peer "github.com/libp2p/go-libp2p-core/peer"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const iso8601 = "2006-01-02T15:04:05.999Z07:00"
@@ -186,6 +186,7 @@ func (d *Deal) UnmarshalJSON(b []byte) error {
 // Status is IPFS pin and Filecoin deal status for a given CID.
 type Status struct {
 	Cid     cid.Cid
+	Name    string
 	DagSize uint64
 	Created time.Time
 	Pins    []Pin
@@ -194,6 +195,7 @@ type Status struct {
 
 type statusJson struct {
 	Cid     string `json:"cid"`
+	Name    string `json:"name,omitempty"`
 	DagSize uint64 `json:"dagSize"`
 	Created string `json:"created"`
 	Pins    []Pin  `json:"pins"`
@@ -210,6 +212,7 @@ func (s *Status) UnmarshalJSON(b []byte) error {
 	if err != nil {
 		return err
 	}
+	s.Name = raw.Name
 	s.DagSize = raw.DagSize
 	s.Created, err = time.Parse(iso8601, raw.Created)
 	if err != nil {
@@ -220,19 +223,21 @@ func (s *Status) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
-func (c *client) Status(ctx context.Context, cid cid.Cid) (*Status, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/status/%s", c.cfg.endpoint, cid), nil)
+func (c *client) Status(ctx context.Context, cid cid.Cid) (_ *Status, err error) {
+	ctx, span := c.startSpan(ctx, "Status", attribute.String("cid", cid.String()))
+	defer func() { endSpan(span, err) }()
+
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("/status/%s", cid), nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.cfg.token))
-	req.Header.Add("X-Client", clientName)
 	res, err := c.cfg.hc.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	defer res.Body.Close()
 	if res.StatusCode != 200 {
-		return nil, fmt.Errorf("unexpected response status: %d", res.StatusCode)
+		return nil, newResponseError(res)
 	}
 	var s Status
 	d := json.NewDecoder(res.Body)
@@ -0,0 +1,400 @@
+package w3s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	"github.com/ipfs/go-unixfs"
+	uio "github.com/ipfs/go-unixfs/io"
+	"github.com/ipld/go-car"
+	"github.com/ipld/go-car/util"
+	"github.com/web3-storage/go-w3s-client/adder"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// syncPrefix is the datastore namespace for sync indexes.
+var syncPrefix = ds.NewKey("/w3s/sync")
+
+type syncConfig struct {
+	name string
+	key  string
+}
+
+// Snapshot is a synced copy of a directory.
+type Snapshot struct {
+	// Root is the CID of the directory.
+	Root cid.Cid `json:"root"`
+	// Previous is the root of the snapshot before this one, if any.
+	Previous cid.Cid `json:"previous"`
+	// Created is the time the snapshot was taken.
+	Created time.Time `json:"created"`
+	// Files is the number of files in the directory.
+	Files int `json:"files"`
+	// Changed is the number of files that were new or modified since the
+	// previous snapshot, and were imported again.
+	Changed int `json:"changed"`
+	// Removed is the number of files in the previous snapshot that no longer
+	// exist.
+	Removed int `json:"removed"`
+	// Blocks is the number of blocks uploaded.
+	Blocks int `json:"blocks"`
+}
+
+// Sync uploads the directory at dir, importing and sending only what changed
+// since the last time it was synced.
+//
+// An index of the size, modification time and CID of each file is kept in the
+// datastore the client was configured with, so unchanged files are not read
+// again and their DAGs are reused. The upload is a CAR containing only the
+// blocks not reachable from the previous snapshot's unchanged subtrees. Sync
+// fails if the client has no datastore, and for the index to outlive the
+// process it must be a persistent one, for example using WithDatastorePath.
+// Blocks imported by Sync are kept even if WithUploadCleanup is set.
+func (c *client) Sync(ctx context.Context, dir string, options ...SyncOption) (snap *Snapshot, err error) {
+	ctx, span := c.startSpan(ctx, "Sync")
+	defer func() { endSpan(span, err) }()
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	cfg := syncConfig{key: abs}
+	for _, opt := range options {
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.Open(abs)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("sync %s: not a directory", dir)
+	}
+
+	if c.bsvc == nil {
+		return nil, errors.New("sync requires a datastore, see WithDatastore and WithDatastorePath")
+	}
+	dag := merkledag.NewDAGService(c.bsvc)
+	idx, err := loadSyncIndex(ctx, c.cfg.ds, dag, cfg.key)
+	if err != nil {
+		return nil, fmt.Errorf("loading sync index: %w", err)
+	}
+
+	root, err := c.addFile(ctx, dag, f, &putConfig{dirname: filepath.Dir(abs)}, adder.WithCache(idx))
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("cid", root.String()))
+
+	snap = &Snapshot{
+		Root:    root,
+		Created: time.Now(),
+		Files:   len(idx.next),
+		Changed: idx.changed,
+	}
+	for p := range idx.prev {
+		if _, ok := idx.next[p]; !ok {
+			snap.Removed++
+		}
+	}
+	if idx.last != nil {
+		snap.Previous = idx.last.Root
+	}
+
+	if idx.last == nil || idx.last.Root != root {
+		synced, err := syncedCids(ctx, dag, idx.last)
+		if err != nil {
+			return nil, err
+		}
+		uploaded := func(c cid.Cid) (bool, error) {
+			return synced.Has(c), nil
+		}
+		snap.Blocks, err = c.putDelta(ctx, dag, []cid.Cid{root}, uploaded, cfg.name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	span.SetAttributes(
+		attribute.Int("files", snap.Files),
+		attribute.Int("changed", snap.Changed),
+		attribute.Int("blocks", snap.Blocks),
+	)
+
+	err = idx.save(ctx, snap)
+	if err != nil {
+		return nil, fmt.Errorf("saving sync index: %w", err)
+	}
+	c.cfg.log.Infof("synced %s as %s: %d files, %d changed, %d removed, %d blocks uploaded", dir, root, snap.Files, snap.Changed, snap.Removed, snap.Blocks)
+	return snap, nil
+}
+
+// putDelta uploads the blocks of the DAGs with the passed roots as one CAR
+// with every root in its header, not descending into links for which uploaded
+// returns true (if it is not nil), and returns the number of blocks sent. The
+// delta is a partial DAG, so rather than splitting it with carbites (which
+// needs the whole DAG of a single root) it is packed into shards as it is
+// walked.
+func (c *client) putDelta(ctx context.Context, dag ipld.DAGService, roots []cid.Cid, uploaded func(cid.Cid) (bool, error), name string) (int, error) {
+	cfg := putConfig{name: name}
+	header := &car.CarHeader{Roots: roots, Version: 1}
+	var buf bytes.Buffer
+	if err := car.WriteHeader(header, &buf); err != nil {
+		return 0, err
+	}
+	headerSize := buf.Len()
+
+	var blocks, shard int
+	send := func() error {
+		_, err := c.sendCar(ctx, &buf, &cfg, shard)
+		if err != nil {
+			return err
+		}
+		shard++
+		buf.Reset()
+		return car.WriteHeader(header, &buf)
+	}
+
+	getLinks := func(ctx context.Context, k cid.Cid) ([]*ipld.Link, error) {
+		nd, err := dag.Get(ctx, k)
+		if err != nil {
+			return nil, err
+		}
+		if buf.Len() > headerSize && buf.Len()+len(nd.RawData()) > targetChunkSize {
+			if err := send(); err != nil {
+				return nil, err
+			}
+		}
+		if err := util.LdWrite(&buf, nd.Cid().Bytes(), nd.RawData()); err != nil {
+			return nil, err
+		}
+		blocks++
+
+		if uploaded == nil {
+			return nd.Links(), nil
+		}
+		var links []*ipld.Link
+		for _, l := range nd.Links() {
+			done, err := uploaded(l.Cid)
+			if err != nil {
+				return nil, err
+			}
+			if !done {
+				links = append(links, l)
+			}
+		}
+		return links, nil
+	}
+
+	visited := cid.NewSet()
+	for _, root := range roots {
+		err := merkledag.Walk(ctx, getLinks, root, visited.Visit)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err := send(); err != nil {
+		return 0, err
+	}
+	return blocks, nil
+}
+
+// syncedCids returns the CIDs of the directories and files in the snapshot,
+// all of which have been uploaded. It is empty if there is no snapshot or its
+// blocks are no longer held locally.
+func syncedCids(ctx context.Context, dag ipld.DAGService, snap *Snapshot) (*cid.Set, error) {
+	set := cid.NewSet()
+	if snap == nil {
+		return set, nil
+	}
+	var visit func(c cid.Cid) error
+	visit = func(c cid.Cid) error {
+		if !set.Visit(c) {
+			return nil
+		}
+		nd, err := dag.Get(ctx, c)
+		if err != nil {
+			return err
+		}
+		pn, ok := nd.(*merkledag.ProtoNode)
+		if !ok {
+			return nil
+		}
+		fsn, err := unixfs.FSNodeFromBytes(pn.Data())
+		if err != nil || !fsn.IsDir() {
+			return nil
+		}
+		dir, err := uio.NewDirectoryFromNode(dag, pn)
+		if err != nil {
+			return err
+		}
+		return dir.ForEachLink(ctx, func(l *ipld.Link) error {
+			return visit(l.Cid)
+		})
+	}
+	err := visit(snap.Root)
+	if errors.Is(err, ipld.ErrNotFound{}) {
+		return cid.NewSet(), nil
+	}
+	return set, err
+}
+
+// syncEntry is the indexed state of a file.
+type syncEntry struct {
+	Size  int64  `json:"size"`
+	Mtime int64  `json:"mtime"`
+	Cid   string `json:"cid"`
+}
+
+func newSyncEntry(info fs.FileInfo, c cid.Cid) syncEntry {
+	return syncEntry{info.Size(), info.ModTime().UnixNano(), c.String()}
+}
+
+// syncIndex is an adder.Cache backed by the entries of the previous sync.
+type syncIndex struct {
+	ctx    context.Context
+	store  ds.Batching
+	dag    ipld.DAGService
+	prefix ds.Key
+
+	last    *Snapshot
+	prev    map[string]syncEntry
+	next    map[string]syncEntry
+	changed int
+}
+
+func loadSyncIndex(ctx context.Context, store ds.Batching, dag ipld.DAGService, key string) (*syncIndex, error) {
+	idx := &syncIndex{
+		ctx:    ctx,
+		store:  store,
+		dag:    dag,
+		prefix: syncPrefix.ChildString(url.PathEscape(key)),
+		prev:   map[string]syncEntry{},
+		next:   map[string]syncEntry{},
+	}
+
+	b, err := store.Get(ctx, idx.snapshotKey())
+	if err == nil {
+		var snap Snapshot
+		if err := json.Unmarshal(b, &snap); err != nil {
+			return nil, err
+		}
+		idx.last = &snap
+	} else if !errors.Is(err, ds.ErrNotFound) {
+		return nil, err
+	}
+
+	res, err := store.Query(ctx, query.Query{Prefix: idx.filesKey().String()})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	for r := range res.Next() {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		p, err := url.PathUnescape(strings.TrimPrefix(r.Key, idx.filesKey().String()+"/"))
+		if err != nil {
+			return nil, err
+		}
+		var e syncEntry
+		if err := json.Unmarshal(r.Value, &e); err != nil {
+			return nil, err
+		}
+		idx.prev[p] = e
+	}
+	return idx, nil
+}
+
+func (idx *syncIndex) snapshotKey() ds.Key {
+	return idx.prefix.ChildString("snapshot")
+}
+
+func (idx *syncIndex) filesKey() ds.Key {
+	return idx.prefix.ChildString("files")
+}
+
+func (idx *syncIndex) fileKey(path string) ds.Key {
+	return idx.filesKey().ChildString(url.PathEscape(path))
+}
+
+func (idx *syncIndex) Get(path string, info fs.FileInfo) (ipld.Node, bool) {
+	e, ok := idx.prev[path]
+	if !ok || e.Size != info.Size() || e.Mtime != info.ModTime().UnixNano() {
+		return nil, false
+	}
+	c, err := cid.Parse(e.Cid)
+	if err != nil {
+		return nil, false
+	}
+	// The DAG can only be reused if its blocks are still held.
+	nd, err := idx.dag.Get(idx.ctx, c)
+	if err != nil {
+		return nil, false
+	}
+	idx.next[path] = e
+	return nd, true
+}
+
+func (idx *syncIndex) Put(path string, info fs.FileInfo, nd ipld.Node) {
+	idx.next[path] = newSyncEntry(info, nd.Cid())
+	idx.changed++
+}
+
+// save writes the entries of this sync and the snapshot, replacing the
+// previous index.
+func (idx *syncIndex) save(ctx context.Context, snap *Snapshot) error {
+	b, err := idx.store.Batch(ctx)
+	if err != nil {
+		return err
+	}
+	for p := range idx.prev {
+		if _, ok := idx.next[p]; !ok {
+			if err := b.Delete(ctx, idx.fileKey(p)); err != nil {
+				return err
+			}
+		}
+	}
+	for p, e := range idx.next {
+		if idx.prev[p] == e {
+			continue
+		}
+		v, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(ctx, idx.fileKey(p), v); err != nil {
+			return err
+		}
+	}
+	v, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	if err := b.Put(ctx, idx.snapshotKey(), v); err != nil {
+		return err
+	}
+	return b.Commit(ctx)
+}
+
+var _ adder.Cache = (*syncIndex)(nil)
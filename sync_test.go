@@ -0,0 +1,105 @@
+package w3s
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/web3-storage/go-w3s-client/w3stest"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}
+
+func TestSync(t *testing.T) {
+	srv := w3stest.NewServer(w3stest.WithToken(validToken))
+	defer srv.Close()
+	store := dssync.MutexWrap(ds.NewMapDatastore())
+	newClient := func() Client {
+		client, err := NewClient(WithEndpoint(srv.URL), WithToken(validToken), WithDatastore(store))
+		if err != nil {
+			t.Fatalf("failed to create client: %v", err)
+		}
+		return client
+	}
+	ctx := context.Background()
+
+	dir := filepath.Join(t.TempDir(), "backup")
+	for i := 0; i < 5; i++ {
+		writeTestFile(t, filepath.Join(dir, fmt.Sprintf("file%d.txt", i)), fmt.Sprintf("content %d", i))
+		writeTestFile(t, filepath.Join(dir, "sub", fmt.Sprintf("file%d.txt", i)), fmt.Sprintf("nested %d", i))
+	}
+
+	first, err := newClient().Sync(ctx, dir)
+	if err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	if first.Files != 10 || first.Changed != 10 {
+		t.Fatalf("got %d files and %d changed, wanted 10 and 10", first.Files, first.Changed)
+	}
+	if !srv.HasUpload(first.Root) {
+		t.Fatalf("snapshot %s was not uploaded", first.Root)
+	}
+
+	// A new client with the same datastore reuses the index.
+	same, err := newClient().Sync(ctx, dir)
+	if err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	if same.Root != first.Root || same.Changed != 0 || same.Blocks != 0 {
+		t.Fatalf("got root %s, %d changed and %d blocks, wanted %s, 0 and 0", same.Root, same.Changed, same.Blocks, first.Root)
+	}
+
+	writeTestFile(t, filepath.Join(dir, "sub", "file0.txt"), "nested 0 changed")
+	if err := os.Remove(filepath.Join(dir, "file4.txt")); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	changed, err := newClient().Sync(ctx, dir)
+	if err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	if changed.Changed != 1 || changed.Removed != 1 || changed.Files != 9 {
+		t.Fatalf("got %d changed, %d removed and %d files, wanted 1, 1 and 9", changed.Changed, changed.Removed, changed.Files)
+	}
+	if changed.Previous != first.Root {
+		t.Fatalf("got previous %s, wanted %s", changed.Previous, first.Root)
+	}
+	// The root, sub directory and changed file.
+	if changed.Blocks != 3 {
+		t.Fatalf("got %d blocks uploaded, wanted %d", changed.Blocks, 3)
+	}
+
+	// The delta and earlier upload together hold the whole DAG.
+	res, err := newClient().Get(ctx, changed.Root)
+	if err != nil {
+		t.Fatalf("failed to get snapshot: %v", err)
+	}
+	if res.StatusCode != 200 {
+		t.Fatalf("got status %d, wanted %d", res.StatusCode, 200)
+	}
+	_, fsys, err := res.Files()
+	if err != nil {
+		t.Fatalf("failed to read files: %v", err)
+	}
+	b, err := fs.ReadFile(fsys, "/sub/file0.txt")
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(b) != "nested 0 changed" {
+		t.Fatalf("got %q, wanted %q", b, "nested 0 changed")
+	}
+	if _, err := fs.Stat(fsys, "/file4.txt"); err == nil {
+		t.Fatalf("removed file is in the snapshot")
+	}
+}
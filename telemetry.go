@@ -0,0 +1,127 @@
+package w3s
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/web3-storage/go-w3s-client/logging"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/instrument/syncfloat64"
+	"go.opentelemetry.io/otel/metric/instrument/syncint64"
+	"go.opentelemetry.io/otel/metric/unit"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/web3-storage/go-w3s-client"
+
+type telemetry struct {
+	tracer trace.Tracer
+	// uploadBytes counts bytes of CAR data sent to the API.
+	uploadBytes syncint64.Counter
+	// shardDuration records the time taken to upload each CAR shard.
+	shardDuration syncfloat64.Histogram
+	// shardThroughput records the upload speed of each CAR shard.
+	shardThroughput syncfloat64.Histogram
+	// retries counts requests retried after a failure.
+	retries syncint64.Counter
+}
+
+func newTelemetry(tp trace.TracerProvider, mp metric.MeterProvider) (*telemetry, error) {
+	meter := mp.Meter(instrumentationName)
+	uploadBytes, err := meter.SyncInt64().Counter(
+		"w3s.client.upload.bytes",
+		instrument.WithUnit(unit.Bytes),
+		instrument.WithDescription("Bytes of CAR data uploaded"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	shardDuration, err := meter.SyncFloat64().Histogram(
+		"w3s.client.shard.duration",
+		instrument.WithUnit(unit.Milliseconds),
+		instrument.WithDescription("Time taken to upload a CAR shard"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	shardThroughput, err := meter.SyncFloat64().Histogram(
+		"w3s.client.shard.throughput",
+		instrument.WithUnit("By/s"),
+		instrument.WithDescription("Upload speed of a CAR shard"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	retries, err := meter.SyncInt64().Counter(
+		"w3s.client.retries",
+		instrument.WithDescription("Requests retried after a failure"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &telemetry{
+		tracer:          tp.Tracer(instrumentationName),
+		uploadBytes:     uploadBytes,
+		shardDuration:   shardDuration,
+		shardThroughput: shardThroughput,
+		retries:         retries,
+	}, nil
+}
+
+// recordShard records metrics for a CAR shard upload of size bytes that took
+// duration d.
+func (t *telemetry) recordShard(ctx context.Context, size int64, d time.Duration) {
+	t.uploadBytes.Add(ctx, size)
+	t.shardDuration.Record(ctx, float64(d)/float64(time.Millisecond))
+	if d > 0 {
+		t.shardThroughput.Record(ctx, float64(size)/d.Seconds())
+	}
+}
+
+func (c *client) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return c.tel.tracer.Start(ctx, "w3s."+name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends the span, recording the error if not nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil && err != io.EOF {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// clientTransport logs the response status code of each request and records it
+// on the span in the request context.
+type clientTransport struct {
+	next http.RoundTripper
+	log  logging.Logger
+}
+
+func (t clientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.log.Warnf("%s %s: %v", req.Method, req.URL.Path, err)
+		return nil, err
+	}
+	t.log.Debugf("%s %s: %d", req.Method, req.URL.Path, res.StatusCode)
+	trace.SpanFromContext(req.Context()).SetAttributes(attribute.Int("http.status_code", res.StatusCode))
+	return res, nil
+}
+
+// spanBody ends the span when the response body is closed.
+type spanBody struct {
+	io.ReadCloser
+	span trace.Span
+}
+
+func (b *spanBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.span.End()
+	return err
+}
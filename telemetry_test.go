@@ -0,0 +1,69 @@
+package w3s
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestPutCarSpans(t *testing.T) {
+	routes := routeMap{
+		"/car": {
+			http.MethodPost: putCarHandler,
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken), WithTracerProvider(tp))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	carbytes, err := hex.DecodeString(helloCarHex)
+	if err != nil {
+		t.Fatalf("failed to decode car hex: %v", err)
+	}
+
+	_, err = client.PutCar(context.Background(), bytes.NewReader(carbytes))
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, wanted %d", len(spans), 2)
+	}
+
+	shard := spans[0]
+	if shard.Name() != "w3s.sendCar" {
+		t.Fatalf("got span %s, wanted %s", shard.Name(), "w3s.sendCar")
+	}
+	if shard.Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Fatalf("expected shard span to be a child of the PutCar span")
+	}
+
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range shard.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["cid"].AsString() != helloRoot {
+		t.Fatalf("got cid attribute %s, wanted %s", attrs["cid"].AsString(), helloRoot)
+	}
+	if attrs["http.status_code"].AsInt64() != http.StatusOK {
+		t.Fatalf("got status code attribute %d, wanted %d", attrs["http.status_code"].AsInt64(), http.StatusOK)
+	}
+	if attrs["bytes"].AsInt64() == 0 {
+		t.Fatalf("missing bytes attribute")
+	}
+}
@@ -0,0 +1,185 @@
+package w3s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrQuotaExceeded is returned by Put when the WithQuotaCheck option is used
+// and the upload would exceed the storage remaining for the account.
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// StorageUsage is the storage used by an account in bytes.
+type StorageUsage struct {
+	// Uploaded is the storage used by uploads.
+	Uploaded uint64
+	// PsaPinned is the storage used by pin requests.
+	PsaPinned uint64
+}
+
+// Total returns the total storage used in bytes.
+func (u StorageUsage) Total() uint64 {
+	return u.Uploaded + u.PsaPinned
+}
+
+// User is account details and storage usage for the user the auth token
+// belongs to.
+type User struct {
+	ID            string
+	Name          string
+	Email         string
+	PublicAddress string
+	Created       time.Time
+	UsedStorage   StorageUsage
+	// StorageLimit is the maximum storage available to the account in bytes.
+	// It is zero if the API did not report a limit.
+	StorageLimit uint64
+}
+
+// StorageRemaining returns the storage still available to the account in
+// bytes. The second return value is false if the account has no known limit.
+func (u *User) StorageRemaining() (uint64, bool) {
+	if u.StorageLimit == 0 {
+		return 0, false
+	}
+	used := u.UsedStorage.Total()
+	if used >= u.StorageLimit {
+		return 0, true
+	}
+	return u.StorageLimit - used, true
+}
+
+type userInfoJson struct {
+	Info struct {
+		ID            string `json:"_id"`
+		Name          string `json:"name"`
+		Email         string `json:"email"`
+		PublicAddress string `json:"publicAddress"`
+		Created       string `json:"created"`
+		Tags          struct {
+			// The limit is serialized as a string or a number depending on the
+			// API version.
+			StorageLimitBytes json.RawMessage `json:"StorageLimitBytes"`
+		} `json:"tags"`
+	} `json:"info"`
+}
+
+type userAccountJson struct {
+	UsedStorage struct {
+		Uploaded  uint64 `json:"uploaded"`
+		PsaPinned uint64 `json:"psaPinned"`
+	} `json:"usedStorage"`
+}
+
+// User retrieves account details and storage usage for the user.
+func (c *client) User(ctx context.Context) (*User, error) {
+	var info userInfoJson
+	err := c.getUserJSON(ctx, "/user/info", &info)
+	if err != nil {
+		return nil, err
+	}
+
+	var account userAccountJson
+	err = c.getUserJSON(ctx, "/user/account", &account)
+	if err != nil {
+		return nil, err
+	}
+
+	u := User{
+		ID:            info.Info.ID,
+		Name:          info.Info.Name,
+		Email:         info.Info.Email,
+		PublicAddress: info.Info.PublicAddress,
+		UsedStorage: StorageUsage{
+			Uploaded:  account.UsedStorage.Uploaded,
+			PsaPinned: account.UsedStorage.PsaPinned,
+		},
+	}
+	if info.Info.Created != "" {
+		u.Created, err = time.Parse(iso8601, info.Info.Created)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if limit := info.Info.Tags.StorageLimitBytes; len(limit) > 0 && string(limit) != "null" {
+		var s string
+		if json.Unmarshal(limit, &s) != nil {
+			s = string(limit)
+		}
+		u.StorageLimit, err = strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing storage limit: %w", err)
+		}
+	}
+	return &u, nil
+}
+
+// Token is an API token belonging to the user.
+type Token struct {
+	ID      string
+	Name    string
+	Secret  string
+	Created time.Time
+}
+
+type tokenJson struct {
+	ID      string `json:"_id"`
+	Name    string `json:"name"`
+	Secret  string `json:"secret"`
+	Created string `json:"created"`
+}
+
+func (t *Token) UnmarshalJSON(b []byte) error {
+	var raw tokenJson
+	err := json.Unmarshal(b, &raw)
+	if err != nil {
+		return err
+	}
+	t.ID = raw.ID
+	t.Name = raw.Name
+	t.Secret = raw.Secret
+	if raw.Created != "" {
+		t.Created, err = time.Parse(iso8601, raw.Created)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tokens retrieves the list of API tokens belonging to the user.
+func (c *client) Tokens(ctx context.Context) ([]Token, error) {
+	var tokens []Token
+	err := c.getUserJSON(ctx, "/user/tokens", &tokens)
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (c *client) getUserJSON(ctx context.Context, path string, v interface{}) error {
+	req, err := c.newRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return err
+	}
+	res, err := c.cfg.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return newResponseError(res)
+	}
+
+	d := json.NewDecoder(res.Body)
+	err = d.Decode(v)
+	if err != nil {
+		return fmt.Errorf("decode %s response: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package w3s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var userInfoHandler = func(w http.ResponseWriter, r *http.Request) {
+	if !hasValidToken(w, r) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"info": map[string]interface{}{
+			"_id":     "315318734258474846",
+			"name":    "Test User",
+			"email":   "test@example.org",
+			"created": "2022-02-20T15:04:05.999Z",
+			"tags": map[string]interface{}{
+				"StorageLimitBytes": "1000",
+			},
+		},
+	})
+}
+
+var userAccountHandler = func(w http.ResponseWriter, r *http.Request) {
+	if !hasValidToken(w, r) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"usedStorage": map[string]interface{}{
+			"uploaded":  600,
+			"psaPinned": 300,
+		},
+	})
+}
+
+func TestUserHappyPath(t *testing.T) {
+	routes := routeMap{
+		"/user/info": {
+			http.MethodGet: userInfoHandler,
+		},
+		"/user/account": {
+			http.MethodGet: userAccountHandler,
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	u, err := client.User(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+
+	if u.Email != "test@example.org" {
+		t.Fatalf("got email %s, wanted %s", u.Email, "test@example.org")
+	}
+
+	remaining, ok := u.StorageRemaining()
+	if !ok || remaining != 100 {
+		t.Fatalf("got remaining storage %d (%t), wanted %d", remaining, ok, 100)
+	}
+}
+
+func TestPutQuotaExceeded(t *testing.T) {
+	routes := routeMap{
+		"/user/info": {
+			http.MethodGet: userInfoHandler,
+		},
+		"/user/account": {
+			http.MethodGet: userAccountHandler,
+		},
+		"/car": {
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) {
+				t.Errorf("unexpected upload")
+				putCarHandler(w, r)
+			},
+		},
+	}
+
+	hc, cleanup := startTestServer(t, routes)
+	defer cleanup()
+
+	client, err := NewClient(WithHTTPClient(hc), WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "big.txt")
+	err = os.WriteFile(path, make([]byte, 200), 0644)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open file: %v", err)
+	}
+
+	_, err = client.Put(context.Background(), f, WithQuotaCheck())
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("got error %v, wanted %v", err, ErrQuotaExceeded)
+	}
+}
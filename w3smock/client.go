@@ -0,0 +1,255 @@
+// Package w3smock provides test doubles for the web3.storage client: a fake
+// Client whose behaviour is configured per method and which records the calls
+// made to it, and a Recorder that captures real API traffic to a golden file
+// and replays it so integration tests can run offline.
+package w3smock
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	w3s "github.com/web3-storage/go-w3s-client"
+	w3http "github.com/web3-storage/go-w3s-client/http"
+)
+
+// ErrNotImplemented is returned by a Client method with no behaviour set.
+var ErrNotImplemented = errors.New("not implemented")
+
+// Call is a call made to a Client. Args are the arguments passed to the
+// method, excluding the context.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// Client is a fake w3s.Client. Each method calls the corresponding function
+// field, or returns ErrNotImplemented if it is nil. All calls are recorded and
+// can be inspected with Calls. It is safe for concurrent use.
+type Client struct {
+	GetFunc        func(ctx context.Context, c cid.Cid) (*w3http.Web3Response, error)
+	PutFunc        func(ctx context.Context, file fs.File, options ...w3s.PutOption) ([]cid.Cid, error)
+	PutCarFunc     func(ctx context.Context, car io.Reader, options ...w3s.PutOption) ([]cid.Cid, error)
+	StatusFunc     func(ctx context.Context, c cid.Cid) (*w3s.Status, error)
+	ListFunc       func(ctx context.Context, options ...w3s.ListOption) (*w3s.UploadIterator, error)
+	DeleteFunc     func(ctx context.Context, c cid.Cid) (*w3s.DeleteResult, error)
+	RenameFunc     func(ctx context.Context, c cid.Cid, name string) (*w3s.RenameResult, error)
+	UserFunc       func(ctx context.Context) (*w3s.User, error)
+	TokensFunc     func(ctx context.Context) ([]w3s.Token, error)
+	PinFunc        func(ctx context.Context, c cid.Cid, options ...w3s.PinOption) (*w3s.PinResponse, error)
+	PinAndWaitFunc func(ctx context.Context, c cid.Cid, options ...w3s.PinWaitOption) (*w3s.PinResponse, error)
+	ListPinsFunc   func(ctx context.Context, options ...w3s.ListPinsOption) (*w3s.PinIterator, error)
+	GetPinFunc     func(ctx context.Context, requestID string) (*w3s.PinResponse, error)
+	ReplacePinFunc func(ctx context.Context, requestID string, c cid.Cid, options ...w3s.PinOption) (*w3s.PinResponse, error)
+	DeletePinFunc  func(ctx context.Context, requestID string) error
+	SyncFunc       func(ctx context.Context, dir string, options ...w3s.SyncOption) (*w3s.Snapshot, error)
+	BlockstoreFunc func() blockstore.Blockstore
+	PutDagFunc     func(ctx context.Context, node datamodel.Node, codec uint64, options ...w3s.PutOption) (cid.Cid, error)
+	GetDagFunc     func(ctx context.Context, c cid.Cid) (*w3http.Dag, error)
+	CloseFunc      func() error
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+var _ w3s.Client = (*Client)(nil)
+
+func (m *Client) record(method string, args ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, Call{Method: method, Args: args})
+}
+
+// Calls returns the calls made to the client, in order.
+func (m *Client) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// CallsTo returns the calls made to the named method, in order.
+func (m *Client) CallsTo(method string) []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var calls []Call
+	for _, c := range m.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Reset clears the recorded calls.
+func (m *Client) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = nil
+}
+
+func (m *Client) Get(ctx context.Context, c cid.Cid) (*w3http.Web3Response, error) {
+	m.record("Get", c)
+	if m.GetFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return m.GetFunc(ctx, c)
+}
+
+func (m *Client) Put(ctx context.Context, file fs.File, options ...w3s.PutOption) ([]cid.Cid, error) {
+	m.record("Put", file, options)
+	if m.PutFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return m.PutFunc(ctx, file, options...)
+}
+
+func (m *Client) PutCar(ctx context.Context, car io.Reader, options ...w3s.PutOption) ([]cid.Cid, error) {
+	m.record("PutCar", car, options)
+	if m.PutCarFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return m.PutCarFunc(ctx, car, options...)
+}
+
+func (m *Client) Status(ctx context.Context, c cid.Cid) (*w3s.Status, error) {
+	m.record("Status", c)
+	if m.StatusFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return m.StatusFunc(ctx, c)
+}
+
+func (m *Client) List(ctx context.Context, options ...w3s.ListOption) (*w3s.UploadIterator, error) {
+	m.record("List", options)
+	if m.ListFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return m.ListFunc(ctx, options...)
+}
+
+func (m *Client) Delete(ctx context.Context, c cid.Cid) (*w3s.DeleteResult, error) {
+	m.record("Delete", c)
+	if m.DeleteFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return m.DeleteFunc(ctx, c)
+}
+
+func (m *Client) Rename(ctx context.Context, c cid.Cid, name string) (*w3s.RenameResult, error) {
+	m.record("Rename", c, name)
+	if m.RenameFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return m.RenameFunc(ctx, c, name)
+}
+
+func (m *Client) User(ctx context.Context) (*w3s.User, error) {
+	m.record("User")
+	if m.UserFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return m.UserFunc(ctx)
+}
+
+func (m *Client) Tokens(ctx context.Context) ([]w3s.Token, error) {
+	m.record("Tokens")
+	if m.TokensFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return m.TokensFunc(ctx)
+}
+
+func (m *Client) Pin(ctx context.Context, c cid.Cid, options ...w3s.PinOption) (*w3s.PinResponse, error) {
+	m.record("Pin", c, options)
+	if m.PinFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return m.PinFunc(ctx, c, options...)
+}
+
+func (m *Client) PinAndWait(ctx context.Context, c cid.Cid, options ...w3s.PinWaitOption) (*w3s.PinResponse, error) {
+	m.record("PinAndWait", c, options)
+	if m.PinAndWaitFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return m.PinAndWaitFunc(ctx, c, options...)
+}
+
+func (m *Client) ListPins(ctx context.Context, options ...w3s.ListPinsOption) (*w3s.PinIterator, error) {
+	m.record("ListPins", options)
+	if m.ListPinsFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return m.ListPinsFunc(ctx, options...)
+}
+
+func (m *Client) GetPin(ctx context.Context, requestID string) (*w3s.PinResponse, error) {
+	m.record("GetPin", requestID)
+	if m.GetPinFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return m.GetPinFunc(ctx, requestID)
+}
+
+func (m *Client) ReplacePin(ctx context.Context, requestID string, c cid.Cid, options ...w3s.PinOption) (*w3s.PinResponse, error) {
+	m.record("ReplacePin", requestID, c, options)
+	if m.ReplacePinFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return m.ReplacePinFunc(ctx, requestID, c, options...)
+}
+
+func (m *Client) DeletePin(ctx context.Context, requestID string) error {
+	m.record("DeletePin", requestID)
+	if m.DeletePinFunc == nil {
+		return ErrNotImplemented
+	}
+	return m.DeletePinFunc(ctx, requestID)
+}
+
+func (m *Client) Sync(ctx context.Context, dir string, options ...w3s.SyncOption) (*w3s.Snapshot, error) {
+	m.record("Sync", dir, options)
+	if m.SyncFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return m.SyncFunc(ctx, dir, options...)
+}
+
+// Blockstore returns nil if BlockstoreFunc is not set.
+func (m *Client) Blockstore() blockstore.Blockstore {
+	m.record("Blockstore")
+	if m.BlockstoreFunc == nil {
+		return nil
+	}
+	return m.BlockstoreFunc()
+}
+
+func (m *Client) PutDag(ctx context.Context, node datamodel.Node, codec uint64, options ...w3s.PutOption) (cid.Cid, error) {
+	m.record("PutDag", node, codec, options)
+	if m.PutDagFunc == nil {
+		return cid.Undef, ErrNotImplemented
+	}
+	return m.PutDagFunc(ctx, node, codec, options...)
+}
+
+func (m *Client) GetDag(ctx context.Context, c cid.Cid) (*w3http.Dag, error) {
+	m.record("GetDag", c)
+	if m.GetDagFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return m.GetDagFunc(ctx, c)
+}
+
+// Close returns nil if CloseFunc is not set.
+func (m *Client) Close() error {
+	m.record("Close")
+	if m.CloseFunc == nil {
+		return nil
+	}
+	return m.CloseFunc()
+}
@@ -0,0 +1,270 @@
+package w3smock
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	w3s "github.com/web3-storage/go-w3s-client"
+)
+
+// Mode determines whether a Recorder records or replays API traffic.
+type Mode int
+
+const (
+	// ModeAuto replays from the golden file if it exists and records to it
+	// otherwise.
+	ModeAuto Mode = iota
+	// ModeRecord sends requests to the API and records the responses,
+	// overwriting the golden file on Close.
+	ModeRecord
+	// ModeReplay serves responses from the golden file without making any
+	// network requests.
+	ModeReplay
+)
+
+// Interaction is a recorded request and the response to it. Requests are
+// matched on replay by method, path, query and a hash of the body, so
+// requests made concurrently are matched to their own responses. Query
+// parameters holding a timestamp, such as the before parameter List sets to
+// the current time, are ignored when matching.
+type Interaction struct {
+	Request struct {
+		Method   string `json:"method"`
+		Path     string `json:"path"`
+		Query    string `json:"query,omitempty"`
+		BodyHash string `json:"bodyHash,omitempty"`
+	} `json:"request"`
+	Response struct {
+		StatusCode int         `json:"statusCode"`
+		Header     http.Header `json:"header,omitempty"`
+		Body       []byte      `json:"body,omitempty"`
+	} `json:"response"`
+
+	used bool
+}
+
+// Recorder records API requests and responses to a golden file and replays
+// them. Pass it to NewRecordingClient or add Recorder.Middleware to a client.
+type Recorder struct {
+	path string
+	mode Mode
+
+	mu           sync.Mutex
+	interactions []*Interaction
+}
+
+// NewRecorder creates a recorder using the golden file at path. In replay
+// mode the file must exist.
+func NewRecorder(path string, mode Mode) (*Recorder, error) {
+	if mode == ModeAuto {
+		mode = ModeRecord
+		if _, err := os.Stat(path); err == nil {
+			mode = ModeReplay
+		}
+	}
+	r := Recorder{path: path, mode: mode}
+	if mode == ModeReplay {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading golden file: %w", err)
+		}
+		if err := json.Unmarshal(b, &r.interactions); err != nil {
+			return nil, fmt.Errorf("decoding golden file: %w", err)
+		}
+	}
+	return &r, nil
+}
+
+// Mode returns the mode of the recorder, which is never ModeAuto.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Middleware returns client middleware that records responses or, in replay
+// mode, serves them from the golden file.
+func (r *Recorder) Middleware() w3s.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		if r.mode == ModeReplay {
+			return w3s.RoundTripperFunc(r.replay)
+		}
+		return w3s.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return r.record(next, req)
+		})
+	}
+}
+
+func (r *Recorder) record(next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	bodyHash, err := hashBody(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var in Interaction
+	in.Request.Method = req.Method
+	in.Request.Path = req.URL.Path
+	in.Request.Query = req.URL.RawQuery
+	in.Request.BodyHash = bodyHash
+	in.Response.StatusCode = res.StatusCode
+	in.Response.Header = res.Header.Clone()
+	in.Response.Header.Del("Set-Cookie")
+	in.Response.Body = body
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, &in)
+	r.mu.Unlock()
+
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return res, nil
+}
+
+// replay serves the first unused recorded response to a request with the same
+// method, path, query and body.
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	bodyHash, err := hashBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	var in *Interaction
+	for _, i := range r.interactions {
+		if !i.used && i.Request.Method == req.Method && i.Request.Path == req.URL.Path &&
+			matchQuery(i.Request.Query, req.URL.RawQuery) && i.Request.BodyHash == bodyHash {
+			i.used = true
+			in = i
+			break
+		}
+	}
+	r.mu.Unlock()
+	if in == nil {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, req.URL.RequestURI())
+	}
+
+	header := in.Response.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+		StatusCode:    in.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(in.Response.Body)),
+		ContentLength: int64(len(in.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// matchQuery reports whether two raw queries are the same once parameters
+// holding a timestamp are removed.
+func matchQuery(recorded, raw string) bool {
+	return normalizeQuery(recorded) == normalizeQuery(raw)
+}
+
+// normalizeQuery encodes the query in key order, without parameters whose
+// values are all RFC 3339 timestamps.
+func normalizeQuery(raw string) string {
+	query, err := url.ParseQuery(raw)
+	if err != nil {
+		return raw
+	}
+	for key, values := range query {
+		if isTimestamp(values) {
+			delete(query, key)
+		}
+	}
+	return query.Encode()
+}
+
+func isTimestamp(values []string) bool {
+	for _, v := range values {
+		if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+			return false
+		}
+	}
+	return len(values) > 0
+}
+
+// hashBody returns the hex SHA-256 hash of the request body, or an empty
+// string if there is none, replacing the body so it can still be sent.
+func hashBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+	defer req.Body.Close()
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading request body: %w", err)
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if len(body) == 0 {
+		return "", nil
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Close writes the recorded interactions to the golden file when recording.
+// When replaying it returns an error if any recorded interactions were not
+// used.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.mode == ModeReplay {
+		var unused int
+		for _, i := range r.interactions {
+			if !i.used {
+				unused++
+			}
+		}
+		if unused > 0 {
+			return fmt.Errorf("%d recorded interactions were not replayed", unused)
+		}
+		return nil
+	}
+	b, err := json.MarshalIndent(r.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, b, 0644)
+}
+
+// NewRecordingClient creates a client that records to or replays from rec.
+// When replaying, no auth token is required.
+func NewRecordingClient(rec *Recorder, options ...w3s.Option) (w3s.Client, error) {
+	if rec == nil {
+		return nil, errors.New("missing recorder")
+	}
+	var opts []w3s.Option
+	if rec.mode == ModeReplay {
+		opts = append(opts, w3s.WithToken("replay"))
+	}
+	opts = append(opts, options...)
+	opts = append(opts, w3s.WithMiddleware(rec.Middleware()))
+	return w3s.NewClient(opts...)
+}
@@ -0,0 +1,275 @@
+package w3smock
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	w3s "github.com/web3-storage/go-w3s-client"
+	"github.com/web3-storage/go-w3s-client/w3stest"
+)
+
+// a car containing a single file called helloword.txt
+const (
+	helloRoot   = "bafybeicymili4gmgoa4xpx5jfghi7leffvai4fd47f6nxgrhq4ug6ekiga"
+	helloCarHex = "3aa265726f6f747381d82a582500017012205862168e1986703977dfa9298e8fac852d408e147cf97cdb9a2787286f1148306776657273696f6e0162017012205862168e1986703977dfa9298e8fac852d408e147cf97cdb9a2787286f11483012380a2401551220315f5bdb76d078c43b8ac0064e4a0164612b1fce77c869345bfc94c75894edd3120e68656c6c6f776f726c642e747874180d0a0208013101551220315f5bdb76d078c43b8ac0064e4a0164612b1fce77c869345bfc94c75894edd348656c6c6f2c20776f726c6421"
+)
+
+func TestClientRecordsCalls(t *testing.T) {
+	root, err := cid.Parse(helloRoot)
+	if err != nil {
+		t.Fatalf("failed to parse cid: %v", err)
+	}
+	m := &Client{
+		StatusFunc: func(ctx context.Context, c cid.Cid) (*w3s.Status, error) {
+			return &w3s.Status{Cid: c, Name: "hello"}, nil
+		},
+		ListFunc: func(ctx context.Context, options ...w3s.ListOption) (*w3s.UploadIterator, error) {
+			return w3s.NewUploadIterator([]*w3s.Status{{Cid: root}}), nil
+		},
+	}
+
+	s, err := m.Status(context.Background(), root)
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	if s.Name != "hello" {
+		t.Fatalf("got name %s, wanted %s", s.Name, "hello")
+	}
+
+	it, err := m.List(context.Background())
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	u, err := it.Next(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get next upload: %v", err)
+	}
+	if !u.Cid.Equals(root) {
+		t.Fatalf("got cid %s, wanted %s", u.Cid, root)
+	}
+
+	_, err = m.Tokens(context.Background())
+	if !errors.Is(err, ErrNotImplemented) {
+		t.Fatalf("got error %v, wanted %v", err, ErrNotImplemented)
+	}
+
+	calls := m.CallsTo("Status")
+	if len(calls) != 1 || calls[0].Args[0] != root {
+		t.Fatalf("got calls %v, wanted one call to Status with %s", calls, root)
+	}
+	if len(m.Calls()) != 3 {
+		t.Fatalf("got %d calls, wanted %d", len(m.Calls()), 3)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "testdata", "put.json")
+	carbytes, err := hex.DecodeString(helloCarHex)
+	if err != nil {
+		t.Fatalf("failed to decode car hex: %v", err)
+	}
+
+	srv := w3stest.NewServer()
+	rec, err := NewRecorder(golden, ModeAuto)
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	if rec.Mode() != ModeRecord {
+		t.Fatalf("got mode %d, wanted %d", rec.Mode(), ModeRecord)
+	}
+	client, err := NewRecordingClient(rec, w3s.WithEndpoint(srv.URL), w3s.WithToken("secret"))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	roots, err := client.PutCar(context.Background(), bytes.NewReader(carbytes))
+	if err != nil {
+		t.Fatalf("failed to put car: %v", err)
+	}
+	root := roots[0]
+	if _, err := client.Status(context.Background(), root); err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("failed to save recording: %v", err)
+	}
+	srv.Close()
+
+	rec, err = NewRecorder(golden, ModeAuto)
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	if rec.Mode() != ModeReplay {
+		t.Fatalf("got mode %d, wanted %d", rec.Mode(), ModeReplay)
+	}
+	client, err = NewRecordingClient(rec, w3s.WithEndpoint(srv.URL))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	replayed, err := client.PutCar(context.Background(), bytes.NewReader(carbytes))
+	if err != nil {
+		t.Fatalf("failed to replay put car: %v", err)
+	}
+	if len(replayed) != 1 || !replayed[0].Equals(root) {
+		t.Fatalf("got cids %v, wanted %s", replayed, root)
+	}
+	s, err := client.Status(context.Background(), root)
+	if err != nil {
+		t.Fatalf("failed to replay status: %v", err)
+	}
+	if !s.Cid.Equals(root) {
+		t.Fatalf("got cid %s, wanted %s", s.Cid, root)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("failed to close replay: %v", err)
+	}
+}
+
+func TestReplayConcurrentUploads(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "puts.json")
+	fsys := fstest.MapFS{}
+	var names []string
+	for i := 0; i < 4; i++ {
+		name := fmt.Sprintf("file%d.txt", i)
+		fsys[name] = &fstest.MapFile{Data: []byte(name)}
+		names = append(names, name)
+	}
+	put := func(client w3s.Client) map[string]cid.Cid {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		roots := map[string]cid.Cid{}
+		for _, name := range names {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				f, err := fsys.Open(name)
+				if err != nil {
+					t.Errorf("failed to open file: %v", err)
+					return
+				}
+				defer f.Close()
+				c, err := client.Put(context.Background(), f)
+				if err != nil {
+					t.Errorf("failed to put %s: %v", name, err)
+					return
+				}
+				mu.Lock()
+				roots[name] = c[0]
+				mu.Unlock()
+			}(name)
+		}
+		wg.Wait()
+		return roots
+	}
+
+	srv := w3stest.NewServer()
+	rec, err := NewRecorder(golden, ModeRecord)
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	client, err := NewRecordingClient(rec, w3s.WithEndpoint(srv.URL), w3s.WithToken("secret"))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	recorded := put(client)
+	if err := rec.Close(); err != nil {
+		t.Fatalf("failed to save recording: %v", err)
+	}
+	srv.Close()
+
+	rec, err = NewRecorder(golden, ModeReplay)
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	client, err = NewRecordingClient(rec, w3s.WithEndpoint(srv.URL))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	replayed := put(client)
+	for _, name := range names {
+		if !replayed[name].Equals(recorded[name]) {
+			t.Fatalf("got cid %s for %s, wanted %s", replayed[name], name, recorded[name])
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("failed to close replay: %v", err)
+	}
+}
+
+func TestReplayList(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "list.json")
+	carbytes, err := hex.DecodeString(helloCarHex)
+	if err != nil {
+		t.Fatalf("failed to decode car hex: %v", err)
+	}
+	list := func(client w3s.Client) []cid.Cid {
+		it, err := client.List(context.Background())
+		if err != nil {
+			t.Fatalf("failed to list uploads: %v", err)
+		}
+		defer it.Close()
+		var roots []cid.Cid
+		for {
+			items, err := it.NextPage(context.Background())
+			if err == io.EOF {
+				return roots
+			}
+			if err != nil {
+				t.Fatalf("failed to get page: %v", err)
+			}
+			for _, s := range items {
+				roots = append(roots, s.Cid)
+			}
+		}
+	}
+
+	srv := w3stest.NewServer()
+	rec, err := NewRecorder(golden, ModeRecord)
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	client, err := NewRecordingClient(rec, w3s.WithEndpoint(srv.URL), w3s.WithToken("secret"))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if _, err := client.PutCar(context.Background(), bytes.NewReader(carbytes)); err != nil {
+		t.Fatalf("failed to put car: %v", err)
+	}
+	recorded := list(client)
+	if err := rec.Close(); err != nil {
+		t.Fatalf("failed to save recording: %v", err)
+	}
+	srv.Close()
+
+	// List sends the current time as the before parameter, which must not
+	// stop the recorded response from being replayed.
+	time.Sleep(10 * time.Millisecond)
+
+	rec, err = NewRecorder(golden, ModeReplay)
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	client, err = NewRecordingClient(rec, w3s.WithEndpoint(srv.URL))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if _, err := client.PutCar(context.Background(), bytes.NewReader(carbytes)); err != nil {
+		t.Fatalf("failed to replay put car: %v", err)
+	}
+	replayed := list(client)
+	if len(recorded) != 1 || len(replayed) != len(recorded) || !replayed[0].Equals(recorded[0]) {
+		t.Fatalf("got uploads %v, wanted %v", replayed, recorded)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("failed to close replay: %v", err)
+	}
+}
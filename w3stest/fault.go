@@ -0,0 +1,126 @@
+package w3stest
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fault describes a fault injected into responses from the server.
+type Fault struct {
+	// Method restricts the fault to requests with this method. Empty matches
+	// any method.
+	Method string
+	// Path restricts the fault to requests whose path has this prefix. Empty
+	// matches any path.
+	Path string
+	// Count is the number of matching requests the fault applies to, after
+	// which it is removed. Zero applies the fault to every matching request.
+	Count int
+
+	// Latency delays the response by the duration.
+	Latency time.Duration
+	// StatusCode, if set, is returned instead of the real response e.g. 500,
+	// 503 or 429.
+	StatusCode int
+	// RetryAfter sets the Retry-After header of the StatusCode response.
+	RetryAfter time.Duration
+	// TruncateAfter, if greater than zero, cuts the response body off after
+	// this many bytes. The Content-Length header reports the full size so the
+	// client sees an unexpected EOF.
+	TruncateAfter int
+}
+
+func (f *Fault) matches(r *http.Request) bool {
+	return (f.Method == "" || f.Method == r.Method) && strings.HasPrefix(r.URL.Path, f.Path)
+}
+
+// AddFault injects a fault into responses for matching requests. Faults are
+// checked in the order added and only the first matching fault is applied.
+func (s *Server) AddFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes all injected faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// SetLatency sets the delay applied to every response.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// takeFault returns the latency to apply to the request and the fault, if
+// any, decrementing its count.
+func (s *Server) takeFault(r *http.Request) (time.Duration, *Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reqs++
+	for i, f := range s.faults {
+		if !f.matches(r) {
+			continue
+		}
+		if f.Count > 0 {
+			f.Count--
+			if f.Count == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return s.latency + f.Latency, f
+	}
+	return s.latency, nil
+}
+
+func (s *Server) withFaults(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		latency, f := s.takeFault(r)
+		if latency > 0 {
+			select {
+			case <-time.After(latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if f == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if f.StatusCode != 0 {
+			if f.RetryAfter > 0 {
+				secs := int((f.RetryAfter + time.Second - 1) / time.Second)
+				w.Header().Set("Retry-After", strconv.Itoa(secs))
+			}
+			writeError(w, f.StatusCode, "FAULT", http.StatusText(f.StatusCode))
+			return
+		}
+
+		if f.TruncateAfter > 0 {
+			rec := httptest.NewRecorder()
+			next.ServeHTTP(rec, r)
+			body := rec.Body.Bytes()
+			for k, v := range rec.Header() {
+				w.Header()[k] = v
+			}
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.WriteHeader(rec.Code)
+			if len(body) > f.TruncateAfter {
+				body = body[:f.TruncateAfter]
+			}
+			bytes.NewReader(body).WriteTo(w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
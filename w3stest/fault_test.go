@@ -0,0 +1,66 @@
+package w3stest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	w3s "github.com/web3-storage/go-w3s-client"
+)
+
+func TestFaults(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	client := newClient(t, srv)
+
+	srv.AddFault(Fault{Method: http.MethodPost, Path: "/car", Count: 1, StatusCode: http.StatusTooManyRequests, RetryAfter: time.Second})
+	fsys := fstest.MapFS{"hello.txt": {Data: []byte("hello")}}
+	f, err := fsys.Open("hello.txt")
+	if err != nil {
+		t.Fatalf("failed to open file: %v", err)
+	}
+	_, err = client.Put(context.Background(), f)
+	var rerr *w3s.ResponseError
+	if !errors.As(err, &rerr) || rerr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("got error %v, wanted status %d", err, http.StatusTooManyRequests)
+	}
+
+	root := putFile(t, client, "hello.txt", "hello")
+
+	srv.AddFault(Fault{Path: "/car/", TruncateAfter: 16})
+	res, err := client.Get(context.Background(), root)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	_, _, err = res.Files()
+	if err == nil {
+		t.Fatalf("got nil error, wanted error reading truncated CAR")
+	}
+}
+
+func TestRetryAfterServerErrors(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	srv.AddFault(Fault{Method: http.MethodPost, Path: "/car", Count: 2, StatusCode: http.StatusServiceUnavailable})
+	client, err := w3s.NewClient(
+		w3s.WithEndpoint(srv.URL),
+		w3s.WithToken(validToken),
+		w3s.WithMaxRetries(2),
+		w3s.WithRetryBackoff(time.Millisecond, 5*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	root := putFile(t, client, "hello.txt", "hello")
+	if !srv.HasUpload(root) {
+		t.Fatalf("upload %s not stored after retries", root)
+	}
+	if srv.Requests() != 3 {
+		t.Fatalf("got %d requests, wanted %d", srv.Requests(), 3)
+	}
+}
@@ -0,0 +1,325 @@
+package w3stest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-cid"
+)
+
+const (
+	defaultPinsLimit = 10
+	maxPinsLimit     = 1000
+)
+
+// Pin request statuses, as defined by the IPFS pinning service API.
+const (
+	PinQueued  = "queued"
+	PinPinning = "pinning"
+	PinPinned  = "pinned"
+	PinFailed  = "failed"
+)
+
+type pinRequest struct {
+	id      string
+	status  string
+	created time.Time
+	cid     cid.Cid
+	name    string
+	origins []string
+	meta    map[string]string
+}
+
+func (p *pinRequest) json() map[string]interface{} {
+	pin := map[string]interface{}{
+		"cid":  p.cid.String(),
+		"name": p.name,
+	}
+	if len(p.origins) > 0 {
+		pin["origins"] = p.origins
+	}
+	if len(p.meta) > 0 {
+		pin["meta"] = p.meta
+	}
+	return map[string]interface{}{
+		"requestId": p.id,
+		"status":    p.status,
+		"created":   p.created.Format(iso8601),
+		"pin":       pin,
+		"delegates": []string{},
+	}
+}
+
+// advance moves the request on to the next status in its lifecycle.
+func (p *pinRequest) advance() {
+	switch p.status {
+	case PinQueued:
+		p.status = PinPinning
+	case PinPinning:
+		p.status = PinPinned
+	}
+}
+
+// SetPinStatus sets the status of a pin request, for example to PinFailed. It
+// returns false if the request does not exist.
+func (s *Server) SetPinStatus(requestID, status string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.findPin(requestID)
+	if p == nil {
+		return false
+	}
+	p.status = status
+	return true
+}
+
+// findPin must be called with the lock held.
+func (s *Server) findPin(id string) *pinRequest {
+	for _, p := range s.pins {
+		if p.id == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// removePin must be called with the lock held.
+func (s *Server) removePin(id string) bool {
+	for i, p := range s.pins {
+		if p.id == id {
+			s.pins = append(s.pins[:i:i], s.pins[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// newPin creates a queued pin request from the request body. It must be called
+// with the lock held.
+func (s *Server) newPin(r *http.Request) (*pinRequest, error) {
+	var body struct {
+		Cid     string            `json:"cid"`
+		Name    string            `json:"name"`
+		Origins []string          `json:"origins"`
+		Meta    map[string]string `json:"meta"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	c, err := cid.Parse(body.Cid)
+	if err != nil {
+		return nil, err
+	}
+	s.nextPinID++
+	p := &pinRequest{
+		id:      strconv.Itoa(s.nextPinID),
+		status:  PinQueued,
+		created: s.now(),
+		cid:     c,
+		name:    body.Name,
+		origins: body.Origins,
+		meta:    body.Meta,
+	}
+	s.pins = append(s.pins, p)
+	return p, nil
+}
+
+func (s *Server) handlePins(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		s.mu.Lock()
+		p, err := s.newPin(r)
+		var out map[string]interface{}
+		if err == nil {
+			out = p.json()
+		}
+		s.mu.Unlock()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_PIN", err.Error())
+			return
+		}
+		writeJSON(w, http.StatusAccepted, out)
+	case http.MethodGet:
+		s.listPins(w, r)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// handlePin serves a single pin request. Each time a request is retrieved its
+// status advances from queued to pinning to pinned, so clients polling for
+// completion see it progress.
+func (s *Server) handlePin(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/pins/")
+
+	s.mu.Lock()
+	p := s.findPin(id)
+	if p == nil {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "pin request not found")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		out := p.json()
+		p.advance()
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, out)
+	case http.MethodPost:
+		np, err := s.newPin(r)
+		var out map[string]interface{}
+		if err == nil {
+			s.removePin(id)
+			out = np.json()
+		}
+		s.mu.Unlock()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_PIN", err.Error())
+			return
+		}
+		writeJSON(w, http.StatusAccepted, out)
+	case http.MethodDelete:
+		s.removePin(id)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		s.mu.Unlock()
+		methodNotAllowed(w, r)
+	}
+}
+
+func (s *Server) listPins(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var cids []cid.Cid
+	if v := q.Get("cid"); v != "" {
+		for _, str := range strings.Split(v, ",") {
+			c, err := cid.Parse(str)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+				return
+			}
+			cids = append(cids, c)
+		}
+	}
+	statuses := []string{PinPinned}
+	if v := q.Get("status"); v != "" {
+		statuses = strings.Split(v, ",")
+	}
+	var before, after time.Time
+	var err error
+	if v := q.Get("before"); v != "" {
+		if before, err = time.Parse(iso8601, v); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+			return
+		}
+	}
+	if v := q.Get("after"); v != "" {
+		if after, err = time.Parse(iso8601, v); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+			return
+		}
+	}
+	limit := defaultPinsLimit
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxPinsLimit {
+			writeError(w, http.StatusBadRequest, "INVALID_QUERY", "invalid limit")
+			return
+		}
+	}
+	var meta map[string]string
+	if v := q.Get("meta"); v != "" {
+		if err := json.Unmarshal([]byte(v), &meta); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+			return
+		}
+	}
+	name, match := q.Get("name"), q.Get("match")
+
+	s.mu.Lock()
+	var matches []*pinRequest
+	for _, p := range s.pins {
+		if len(cids) > 0 && !containsCid(cids, p.cid) {
+			continue
+		}
+		if !containsString(statuses, p.status) {
+			continue
+		}
+		if !before.IsZero() && !p.created.Before(before) {
+			continue
+		}
+		if !after.IsZero() && !p.created.After(after) {
+			continue
+		}
+		if name != "" && !matchName(p.name, name, match) {
+			continue
+		}
+		if !matchMeta(p.meta, meta) {
+			continue
+		}
+		matches = append(matches, p)
+	}
+	// Newest first.
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].created.After(matches[j].created)
+	})
+	results := []interface{}{}
+	for i, p := range matches {
+		if i == limit {
+			break
+		}
+		results = append(results, p.json())
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"count":   len(matches),
+		"results": results,
+	})
+}
+
+func containsCid(cids []cid.Cid, c cid.Cid) bool {
+	for _, x := range cids {
+		if x.Equals(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(strs []string, s string) bool {
+	for _, x := range strs {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// matchName matches a pin name using the text matching strategies of the
+// pinning service API: exact (the default), iexact, partial and ipartial.
+func matchName(name, query, match string) bool {
+	switch match {
+	case "iexact":
+		return strings.EqualFold(name, query)
+	case "partial":
+		return strings.Contains(name, query)
+	case "ipartial":
+		return strings.Contains(strings.ToLower(name), strings.ToLower(query))
+	default:
+		return name == query
+	}
+}
+
+func matchMeta(meta, query map[string]string) bool {
+	for k, v := range query {
+		if meta[k] != v {
+			return false
+		}
+	}
+	return true
+}
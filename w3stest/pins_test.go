@@ -0,0 +1,40 @@
+package w3stest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	w3s "github.com/web3-storage/go-w3s-client"
+)
+
+func TestPins(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	client := newClient(t, srv)
+
+	c, err := cid.Parse("bafybeicymili4gmgoa4xpx5jfghi7leffvai4fd47f6nxgrhq4ug6ekiga")
+	if err != nil {
+		t.Fatalf("failed to parse cid: %v", err)
+	}
+	p, err := client.PinAndWait(context.Background(), c, w3s.WithPinName("hello"), w3s.WithPinPollInterval(time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to pin: %v", err)
+	}
+	if p.Status != w3s.PinRequestPinned {
+		t.Fatalf("got status %s, wanted %s", p.Status, w3s.PinRequestPinned)
+	}
+
+	it, err := client.ListPins(context.Background(), w3s.WithPinNameFilter("hello"))
+	if err != nil {
+		t.Fatalf("failed to list pins: %v", err)
+	}
+	lp, err := it.Next(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get next pin: %v", err)
+	}
+	if lp.RequestID != p.RequestID {
+		t.Fatalf("got request id %s, wanted %s", lp.RequestID, p.RequestID)
+	}
+}
@@ -0,0 +1,155 @@
+// Package w3stest provides an in-memory fake of the web3.storage API for use
+// in tests. It stores uploaded CARs in memory, serves them back along with
+// upload status and listings, tracks pin requests and can inject faults such
+// as latency, error responses and truncated bodies.
+package w3stest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	bserv "github.com/ipfs/go-blockservice"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+)
+
+const iso8601 = "2006-01-02T15:04:05.999Z07:00"
+
+// Server is a fake web3.storage API server. Create one with NewServer and pass
+// its URL to the client with the WithEndpoint option.
+type Server struct {
+	// URL is the base URL of the server, of the form http://ipaddr:port with
+	// no trailing slash.
+	URL string
+
+	ts    *httptest.Server
+	token string
+
+	mu        sync.Mutex
+	bs        blockstore.Blockstore
+	dag       ipld.DAGService
+	uploads   []*upload
+	pins      []*pinRequest
+	faults    []*Fault
+	latency   time.Duration
+	last      time.Time
+	reqs      int
+	nextPinID int
+}
+
+// Option is an option configuring a Server.
+type Option func(s *Server)
+
+// WithToken requires requests to be authorized with the passed bearer token.
+// By default any token is accepted.
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// WithLatency delays every response by d, in addition to the latency of any
+// injected fault.
+func WithLatency(d time.Duration) Option {
+	return func(s *Server) {
+		s.latency = d
+	}
+}
+
+// WithFault injects a fault into responses. See Server.AddFault.
+func WithFault(f Fault) Option {
+	return func(s *Server) {
+		f := f
+		s.faults = append(s.faults, &f)
+	}
+}
+
+// NewServer starts and returns a new fake API server. The caller should call
+// Close when finished, to shut it down.
+func NewServer(options ...Option) *Server {
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	s := &Server{
+		bs:  bs,
+		dag: merkledag.NewDAGService(bserv.New(bs, nil)),
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/car", s.handleCar)
+	mux.HandleFunc("/car/", s.handleCar)
+	mux.HandleFunc("/status/", s.handleStatus)
+	mux.HandleFunc("/user/uploads", s.handleUploads)
+	mux.HandleFunc("/pins", s.handlePins)
+	mux.HandleFunc("/pins/", s.handlePin)
+
+	s.ts = httptest.NewServer(s.withFaults(s.withAuth(mux)))
+	s.URL = s.ts.URL
+	return s
+}
+
+// Close shuts down the server and blocks until all outstanding requests on it
+// have completed.
+func (s *Server) Close() {
+	s.ts.Close()
+}
+
+// Requests returns the number of requests the server has received.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reqs
+}
+
+// now returns the current time, ensuring each call returns a time later than
+// the last so that items can be paginated by creation time. It must be called
+// with the lock held.
+func (s *Server) now() time.Time {
+	t := time.Now().UTC().Truncate(time.Millisecond)
+	if !t.After(s.last) {
+		t = s.last.Add(time.Millisecond)
+	}
+	s.last = t
+	return t
+}
+
+func (s *Server) withAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") || (s.token != "" && auth != "Bearer "+s.token) {
+			writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid auth token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeError writes an error response in the format used by the API.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"name":    "HTTPError",
+		"code":    code,
+		"message": message,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", fmt.Sprintf("method %s not allowed", r.Method))
+}
@@ -0,0 +1,34 @@
+package w3stest
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ipfs/go-cid"
+	w3s "github.com/web3-storage/go-w3s-client"
+)
+
+const validToken = "validtoken"
+
+func newClient(t *testing.T, srv *Server) w3s.Client {
+	client, err := w3s.NewClient(w3s.WithEndpoint(srv.URL), w3s.WithToken(validToken))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return client
+}
+
+func putFile(t *testing.T, client w3s.Client, name, content string) cid.Cid {
+	fsys := fstest.MapFS{name: {Data: []byte(content)}}
+	f, err := fsys.Open(name)
+	if err != nil {
+		t.Fatalf("failed to open file: %v", err)
+	}
+	defer f.Close()
+	roots, err := client.Put(context.Background(), f, w3s.WithName(name))
+	if err != nil {
+		t.Fatalf("failed to put file: %v", err)
+	}
+	return roots[0]
+}
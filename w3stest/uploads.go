@@ -0,0 +1,245 @@
+package w3stest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-car"
+
+	// Register the codecs of structured data, so its links can be walked.
+	_ "github.com/ipld/go-ipld-prime/codec/dagcbor"
+	_ "github.com/ipld/go-ipld-prime/codec/dagjson"
+)
+
+const defaultPageSize = 25
+
+// upload is a CAR that has been uploaded to the server.
+type upload struct {
+	root    cid.Cid
+	name    string
+	dagSize uint64
+	created time.Time
+}
+
+func (u *upload) json() map[string]interface{} {
+	return map[string]interface{}{
+		"cid":     u.root.String(),
+		"name":    u.name,
+		"dagSize": u.dagSize,
+		"created": u.created.Format(iso8601),
+		"pins":    []interface{}{},
+		"deals":   []interface{}{},
+	}
+}
+
+// HasUpload reports whether a CAR with the passed root has been uploaded.
+func (s *Server) HasUpload(root cid.Cid) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findUpload(root) != nil
+}
+
+// findUpload must be called with the lock held.
+func (s *Server) findUpload(root cid.Cid) *upload {
+	for _, u := range s.uploads {
+		if u.root.Equals(root) {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleCar(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/car":
+		s.postCar(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/car/"):
+		s.getCar(w, r)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+func (s *Server) postCar(w http.ResponseWriter, r *http.Request) {
+	cr, err := car.NewCarReader(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_CAR", err.Error())
+		return
+	}
+	if len(cr.Header.Roots) == 0 {
+		writeError(w, http.StatusBadRequest, "INVALID_CAR", "expected at least 1 root")
+		return
+	}
+
+	var size uint64
+	for {
+		blk, err := cr.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			writeError(w, http.StatusBadRequest, "INVALID_CAR", err.Error())
+			return
+		}
+		if err := s.bs.Put(r.Context(), blk); err != nil {
+			writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+			return
+		}
+		size += uint64(len(blk.RawData()))
+	}
+
+	name, _ := url.PathUnescape(r.Header.Get("X-Name"))
+
+	// Each root of the CAR is listed as an upload. Shards of a large upload
+	// share the same roots.
+	s.mu.Lock()
+	for _, root := range cr.Header.Roots {
+		u := s.findUpload(root)
+		if u == nil {
+			u = &upload{root: root, created: time.Now().UTC().Truncate(time.Millisecond)}
+			s.uploads = append(s.uploads, u)
+		}
+		u.dagSize += size
+		if name != "" {
+			u.name = name
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]string{"cid": cr.Header.Roots[0].String()})
+}
+
+func (s *Server) getCar(w http.ResponseWriter, r *http.Request) {
+	root, err := cid.Parse(strings.TrimPrefix(r.URL.Path, "/car/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_CID", err.Error())
+		return
+	}
+	// Write to a buffer first so a missing block results in an error status
+	// rather than a partial CAR.
+	var buf bytes.Buffer
+	if err := car.WriteCar(r.Context(), s.dag, []cid.Cid{root}, &buf); err != nil {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.ipld.car")
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	root, err := cid.Parse(strings.TrimPrefix(r.URL.Path, "/status/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_CID", err.Error())
+		return
+	}
+	s.mu.Lock()
+	u := s.findUpload(root)
+	s.mu.Unlock()
+	if u == nil {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "upload not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, u.json())
+}
+
+func (s *Server) handleUploads(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	q := r.URL.Query()
+
+	var before, after time.Time
+	var err error
+	if v := q.Get("before"); v != "" {
+		if before, err = time.Parse(iso8601, v); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+			return
+		}
+	}
+	if v := q.Get("after"); v != "" {
+		if after, err = time.Parse(iso8601, v); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+			return
+		}
+	}
+	size := defaultPageSize
+	if v := q.Get("size"); v != "" {
+		if size, err = strconv.Atoi(v); err != nil || size < 1 {
+			writeError(w, http.StatusBadRequest, "INVALID_QUERY", "invalid size")
+			return
+		}
+	}
+	page := 1
+	if v := q.Get("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil || page < 1 {
+			writeError(w, http.StatusBadRequest, "INVALID_QUERY", "invalid page")
+			return
+		}
+	}
+	name := q.Get("name")
+
+	s.mu.Lock()
+	var matches []*upload
+	for _, u := range s.uploads {
+		// Timestamps only have millisecond precision so before is inclusive,
+		// otherwise an upload made in the same millisecond as the listing
+		// would be missed.
+		if !before.IsZero() && u.created.After(before) {
+			continue
+		}
+		if !after.IsZero() && u.created.Before(after) {
+			continue
+		}
+		if name != "" && !strings.Contains(u.name, name) {
+			continue
+		}
+		matches = append(matches, u)
+	}
+	s.mu.Unlock()
+
+	desc := q.Get("sortOrder") != "Asc"
+	byName := q.Get("sortBy") == "Name"
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if desc {
+			a, b = b, a
+		}
+		if byName && a.name != b.name {
+			return a.name < b.name
+		}
+		return a.created.Before(b.created)
+	})
+
+	start := (page - 1) * size
+	if start > len(matches) {
+		start = len(matches)
+	}
+	end := start + size
+	if end > len(matches) {
+		end = len(matches)
+	}
+	if end < len(matches) {
+		q.Set("page", strconv.Itoa(page+1))
+		w.Header().Set("Link", fmt.Sprintf(`</user/uploads?%s>; rel="next"`, q.Encode()))
+	}
+
+	items := []interface{}{}
+	for _, u := range matches[start:end] {
+		items = append(items, u.json())
+	}
+	writeJSON(w, http.StatusOK, items)
+}
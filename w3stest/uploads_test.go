@@ -0,0 +1,69 @@
+package w3stest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	w3s "github.com/web3-storage/go-w3s-client"
+)
+
+func TestUploads(t *testing.T) {
+	srv := NewServer(WithToken(validToken))
+	defer srv.Close()
+	client := newClient(t, srv)
+
+	var roots []cid.Cid
+	for i := 0; i < 30; i++ {
+		roots = append(roots, putFile(t, client, fmt.Sprintf("file%02d.txt", i), fmt.Sprintf("content %d", i)))
+	}
+
+	s, err := client.Status(context.Background(), roots[0])
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	if s.Name != "file00.txt" {
+		t.Fatalf("got name %s, wanted %s", s.Name, "file00.txt")
+	}
+
+	it, err := client.List(context.Background(), w3s.WithSortBy(w3s.SortByName), w3s.WithSortOrder(w3s.SortOrderAsc))
+	if err != nil {
+		t.Fatalf("failed to list uploads: %v", err)
+	}
+	var n int
+	for {
+		s, err := it.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to get next upload: %v", err)
+		}
+		if !s.Cid.Equals(roots[n]) {
+			t.Fatalf("got cid %s at %d, wanted %s", s.Cid, n, roots[n])
+		}
+		n++
+	}
+	if n != len(roots) {
+		t.Fatalf("got %d uploads, wanted %d", n, len(roots))
+	}
+
+	res, err := client.Get(context.Background(), roots[1])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	_, fsys, err := res.Files()
+	if err != nil {
+		t.Fatalf("failed to read files: %v", err)
+	}
+	b, err := fs.ReadFile(fsys, "/file01.txt")
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(b) != "content 1" {
+		t.Fatalf("got content %q, wanted %q", b, "content 1")
+	}
+}